@@ -17,23 +17,46 @@
 package cf
 
 import (
+	"encoding"
+	"fmt"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"reflect"
+	"sort"
 	"strings"
 )
 
+// BindYaml binds the yaml file at path. Binding errors are located by the file, line and column of the failing value.
 func BindYaml(cf interface{}, path string, opt *Options) error {
+	return bindFile(cf, path, YamlPositionalDecoder, opt)
+}
+
+// BindJson binds the json file at path. Binding errors are located by the file of the failing value.
+func BindJson(cf interface{}, path string, opt *Options) error {
+	return bindFile(cf, path, withoutOrigins(JsonDecoder), opt)
+}
+
+// BindFile binds the file at path, decoded by the PositionalDecoder or Decoder registered in opt for the file's
+// extension. Binding errors are located by position when the decoder is positional (as for yaml).
+func BindFile(cf interface{}, path string, opt *Options) error {
+	decoder, err := opt.decoder(path)
+	if err != nil {
+		return err
+	}
+	return bindFile(cf, path, decoder, opt)
+}
+
+func bindFile(cf interface{}, path string, decoder PositionalDecoder, opt *Options) error {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return errors.Wrapf(err, "error reading yaml [%s]", path)
+		return errors.Wrapf(err, "error reading [%s]", path)
 	}
-	dataMap := make(map[string]interface{})
-	if err := yaml.Unmarshal(data, dataMap); err != nil {
-		return errors.Wrapf(err, "error parsing yaml [%s]", path)
+	dataMap, origins, err := decoder(path, data)
+	if err != nil {
+		return err
 	}
-	return Bind(cf, dataMap, opt)
+	return locateErrors(Bind(cf, dataMap, opt), origins, Origin{Kind: "file", Name: path})
 }
 
 func Bind(cf interface{}, data map[string]interface{}, opt *Options) error {
@@ -44,184 +67,454 @@ func Bind(cf interface{}, data map[string]interface{}, opt *Options) error {
 	if cfV.Kind() != reflect.Struct {
 		return errors.Errorf("provided type [%s] is not a struct", cfV.Type())
 	}
-	for i := 0; i < cfV.NumField(); i++ {
-		if cfV.Field(i).CanInterface() {
-			fd := parseFieldData(cfV.Type().Field(i), opt)
-			if !fd.skip {
-				if v, found := data[fd.name]; found {
-					if cfV.Field(i).CanSet() {
-						nestedType := cfV.Type().Field(i).Type
-
-						if setter, found := opt.Setters[nestedType]; found {
-							// setter-based type
-							setterv := v
-							if vname, ok := variableReference(v); ok {
-								if vvalue, found := opt.resolveVariable(vname); found {
-									setterv = vvalue
-								} else {
-									return errors.Errorf("unable to resolve variable '${%s}' for field '%v'", vname, fd.name)
-								}
-							}
-							if err := setter(setterv, cfV.Field(i), opt); err != nil {
-								return errors.Wrapf(err, "field '%s'", fd.name)
-							}
-						} else if nestedType.Kind() == reflect.Struct || (nestedType.Kind() == reflect.Ptr && nestedType.Elem().Kind() == reflect.Struct) {
-							// nested structure
-							nested := instantiateAsPtr(nestedType, opt)
-							if subData, ok := v.(map[string]interface{}); ok {
-								err := Bind(nested, subData, opt)
-								if err != nil {
-									return errors.Wrapf(err, "field '%s'", fd.name)
-								}
-							} else {
-								return errors.Errorf("invalid sub map for field '%s'", fd.name)
-							}
+	if opt.self == nil {
+		// root of the data, for "${self:path}" variables
+		rootOpt := *opt
+		rootOpt.self = data
+		opt = &rootOpt
+	}
+	b := &binder{opt: opt}
+	if err := b.bindStruct(cf, data, opt.path); err != nil {
+		return err
+	}
+	return b.result()
+}
 
-							if nestedType.Kind() == reflect.Ptr {
-								// by pointer
-								cfV.Field(i).Set(reflect.ValueOf(nested))
-							} else {
-								// by value
-								cfV.Field(i).Set(reflect.ValueOf(nested).Elem())
-							}
+// CfUnmarshaler is implemented by types that bind themselves from raw configuration data. UnmarshalCf receives the
+// data exactly as found at the type's location (a scalar, a map or a slice), along with the binding options.
+type CfUnmarshaler interface {
+	UnmarshalCf(v interface{}, opt *Options) error
+}
 
-						} else if nestedType.Kind() == reflect.Slice {
-							sliceType := valueFromPtr(nestedType.Elem())
-							if reflect.ValueOf(v).Kind() == reflect.Slice { // the data should also be a slice
-								if sliceType.Kind() != reflect.Interface {
-									for j := 0; j < reflect.ValueOf(v).Len(); j++ { // iterate over the available data
-										elem := instantiateAsPtr(sliceType, opt)
-										sliceV := reflect.ValueOf(v).Index(j).Interface()
-										if handler, found := opt.Setters[sliceType]; found {
-											if err := handler(sliceV, reflect.ValueOf(elem), opt); err != nil {
-												return errors.Wrapf(err, "field '%s'", fd.name)
-											}
-										} else if sliceType.Kind() == reflect.Struct {
-											if subData, ok := sliceV.(map[string]interface{}); ok {
-												if err := Bind(elem, subData, opt); err != nil {
-													return errors.Wrapf(err, "field '%s'", fd.name)
-												}
-											} else {
-												return errors.Errorf("invalid sub map for field '%s'", fd.name)
-											}
-										}
-
-										if nestedType.Elem().Kind() == reflect.Ptr {
-											cfV.Field(i).Set(reflect.Append(cfV.Field(i), reflect.ValueOf(elem)))
-										} else {
-											cfV.Field(i).Set(reflect.Append(cfV.Field(i), reflect.ValueOf(elem).Elem()))
-										}
-									}
-
-								} else {
-									for j := 0; j < reflect.ValueOf(v).Len(); j++ { // iterate over the available data
-										sliceV := reflect.ValueOf(v).Index(j).Interface()
-										if subData, ok := sliceV.(map[string]interface{}); ok {
-											if t, ok := subData["type"]; ok {
-												if t, ok := t.(string); ok {
-													if opt.FlexibleSetters != nil {
-														if fs, found := opt.FlexibleSetters[t]; found {
-															elem, err := fs(sliceV, opt)
-															if err != nil {
-																return errors.Wrapf(err, "flexible setter for field '%v'", fd.name)
-															}
-															cfV.Field(i).Set(reflect.Append(cfV.Field(i), reflect.ValueOf(elem)))
-
-														} else {
-															return errors.Errorf("no flexible setter for field '%v' with type '%v'", fd.name, t)
-														}
-													} else {
-														return errors.Errorf("no flexible setters found for field '%v'", fd.name)
-													}
-												} else {
-													return errors.Errorf("'type' data not string value for field '%v'", fd.name)
-												}
-											} else {
-												return errors.Errorf("'type' data not found string value for field '%v'", fd.name)
-											}
-										} else {
-											return errors.Errorf("not data map for field '%v'", fd.name)
-										}
-									}
-								}
-
-							} else {
-								return errors.Errorf("invalid arr map for field '%s' (%v)", fd.name, reflect.TypeOf(v))
-							}
+var cfUnmarshalerType = reflect.TypeOf((*CfUnmarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 
-						} else if nestedType.Kind() == reflect.Interface {
-							if subData, ok := v.(map[string]interface{}); ok {
-								if t, ok := subData["type"]; ok {
-									if t, ok := t.(string); ok {
-										if opt.FlexibleSetters != nil {
-											if fs, found := opt.FlexibleSetters[t]; found {
-												value, err := fs(v, opt)
-												if err != nil {
-													return errors.Wrapf(err, "flexible setter error for field '%s'", fd.name)
-												}
-												cfV.Field(i).Set(reflect.ValueOf(value))
-											} else {
-												return errors.Errorf("no flexible setter for field '%s' with type '%s'", fd.name, t)
-											}
-										} else {
-											return errors.Errorf("no flexible setters found for field '%s'", fd.name)
-										}
-									} else {
-										return errors.Errorf("'type' data not string value for field '%s'", fd.name)
-									}
-								} else {
-									return errors.Errorf("no 'type' data found for field '%s'", fd.name)
-								}
-							} else {
-								return errors.Errorf("interface{} field '%s' requires sub data map", fd.name)
-							}
+// isUnmarshaler reports whether t (or the type t points to) implements CfUnmarshaler or encoding.TextUnmarshaler.
+func isUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PtrTo(valueFromPtr(t))
+	return pt.Implements(cfUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// unmarshals reports whether t unmarshals the data v itself: a CfUnmarshaler takes any data, while an
+// encoding.TextUnmarshaler only takes scalar data, leaving sub maps to bind field by field.
+func unmarshals(t reflect.Type, v interface{}) bool {
+	pt := reflect.PtrTo(valueFromPtr(t))
+	if pt.Implements(cfUnmarshalerType) {
+		return true
+	}
+	if pt.Implements(textUnmarshalerType) {
+		switch v.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+type binder struct {
+	opt  *Options
+	errs BindErrors
+}
 
-						} else {
-							return errors.Errorf("no setter for field '%s' of type '%s/%v'", fd.name, nestedType, nestedType.Kind())
+func (b *binder) bindStruct(cf interface{}, data map[string]interface{}, path string) error {
+	cfV := reflect.ValueOf(cf)
+	if cfV.Kind() == reflect.Ptr {
+		cfV = cfV.Elem()
+	}
+	structErrCount := len(b.errs)
+	claimed := make(map[string]bool)
+	for i := 0; i < cfV.NumField(); i++ {
+		if cfV.Field(i).CanInterface() {
+			fd := parseFieldData(cfV.Type().Field(i), b.opt)
+			if !fd.skip {
+				claimed[fd.name] = true
+				fieldPath := joinPath(path, fd.name)
+				errCount := len(b.errs)
+				v, found := data[fd.name]
+				if !found && !fd.hasDefault && hasDefaults(cfV.Field(i).Type(), b.opt) {
+					// absent structure by value; bind as empty, to apply the defaults of its fields
+					v = map[string]interface{}{}
+					found = true
+				}
+				if !found && fd.hasDefault {
+					v = defaultData(fd.defaultValue, cfV.Field(i).Type())
+					found = true
+					if b.opt.resolved {
+						// defaults are not part of the resolved data
+						expanded, err := expandVariables(v, b.opt)
+						if err != nil {
+							if err := b.fail(fieldPath, err); err != nil {
+								return err
+							}
+							continue
+						}
+						v = expanded
+					}
+				}
+				if found {
+					if cfV.Field(i).CanSet() {
+						value, err := b.bindValue(v, cfV.Field(i), fieldPath)
+						if err != nil {
+							return err
+						}
+						if value.IsValid() {
+							cfV.Field(i).Set(value)
 						}
 					} else {
-						return errors.Errorf("non-settable field '%s' of type '%s'", fd.name, cfV.Field(i).Type())
+						if err := b.fail(fieldPath, errors.Errorf("non-settable field of type '%s'", cfV.Field(i).Type())); err != nil {
+							return err
+						}
 					}
 				} else {
 					if fd.required {
-						return errors.Errorf("no data found for required field '%s'", fd.name)
+						if err := b.fail(fieldPath, errors.New("no data found for required field")); err != nil {
+							return err
+						}
+					}
+				}
+				if len(b.errs) == errCount {
+					// only validate fields that bound cleanly; absent fields only need to satisfy +nonempty
+					for _, r := range fd.rules {
+						if !found && r.Name != "nonempty" {
+							continue
+						}
+						if err := r.Check(cfV.Field(i)); err != nil {
+							if err := b.fail(fieldPath, err); err != nil {
+								return err
+							}
+						}
 					}
 				}
 			}
 		}
 	}
+	if err := b.checkKeys(data, claimed, path); err != nil {
+		return err
+	}
+	// validate the structure, when it bound cleanly
+	if validator, ok := cf.(Validator); ok && len(b.errs) == structErrCount {
+		if err := validator.Validate(); err != nil {
+			if err := b.fail(path, err); err != nil {
+				return err
+			}
+		}
+	}
 	// execute wirings for type
-	if opt.Wirings != nil {
-		if wirings, found := opt.Wirings[valueFromPtr(reflect.TypeOf(cf))]; found {
+	if b.opt.Wirings != nil {
+		if wirings, found := b.opt.Wirings[cfV.Type()]; found {
 			for _, wiring := range wirings {
 				if err := wiring(cf); err != nil {
-					return errors.Wrapf(err, "error wiring [%s]", cfV.Elem().Type().Name())
+					if err := b.fail(path, errors.Wrapf(err, "error wiring [%s]", cfV.Type().Name())); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkKeys reports the keys in data that are not claimed by any field, when the options ask for it.
+func (b *binder) checkKeys(data map[string]interface{}, claimed map[string]bool, path string) error {
+	if !b.opt.Strict && b.opt.UnknownKeyHandler == nil {
+		return nil
+	}
+	var unknown []string
+	for k := range data {
+		if !claimed[k] && !(k == "type" && b.opt.flexible && path == b.opt.path) {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		if b.opt.UnknownKeyHandler != nil {
+			b.opt.UnknownKeyHandler(joinPath(path, k))
+		}
+		if b.opt.Strict {
+			if err := b.fail(joinPath(path, k), errors.New("unknown key")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindValue produces a value of the type of current from the data in v. The returned value is invalid when binding
+// failed and the failure was recorded; a non-nil error means binding must stop.
+func (b *binder) bindValue(v interface{}, current reflect.Value, path string) (reflect.Value, error) {
+	t := current.Type()
+	if setter, found := b.opt.Setters[t]; found {
+		// setter-based type
+		expanded, err := b.expandVariables(v)
+		if err != nil {
+			return reflect.Value{}, b.fail(path, err)
+		}
+		if vname, ok := variableReference(v); ok && t.Kind() == reflect.String {
+			// string fields take any scalar variable, as inline references do
+			if expanded, err = formatVariable(vname, expanded); err != nil {
+				return reflect.Value{}, b.fail(path, err)
+			}
+		}
+		v = expanded
+		value := reflect.New(t).Elem()
+		if err := setter(v, value, b.opt.at(path)); err != nil {
+			return reflect.Value{}, b.fail(path, err)
+		}
+		return value, nil
+
+	} else if t.Kind() == reflect.Ptr && v == nil {
+		// explicit null
+		return reflect.Zero(t), nil
+
+	} else if unmarshals(t, v) {
+		// type unmarshals itself
+		return b.bindUnmarshaler(v, t, path)
+
+	} else if _, elemSetter := b.opt.Setters[valueFromPtr(t)]; t.Kind() == reflect.Ptr && (t.Elem().Kind() != reflect.Struct || elemSetter) {
+		// pointer to scalar (or other non-structure, or setter-based structure) type
+		elem := reflect.New(t.Elem())
+		value, err := b.bindValue(v, elem.Elem(), path)
+		if err != nil || !value.IsValid() {
+			return reflect.Value{}, err
+		}
+		elem.Elem().Set(value)
+		return elem, nil
+
+	} else if t.Kind() == reflect.Struct || (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) {
+		// nested structure
+		nested := instantiateAsPtr(t, b.opt)
+		if subData, ok := v.(map[string]interface{}); ok {
+			if err := b.bindStruct(nested, subData, path); err != nil {
+				return reflect.Value{}, err
+			}
+		} else {
+			return reflect.Value{}, b.fail(path, errors.Errorf("invalid sub map (%v)", reflect.TypeOf(v)))
+		}
+		if t.Kind() == reflect.Ptr {
+			// by pointer
+			return reflect.ValueOf(nested), nil
+		}
+		// by value
+		return reflect.ValueOf(nested).Elem(), nil
+
+	} else if t.Kind() == reflect.Slice {
+		return b.bindSlice(v, current, path)
+
+	} else if t.Kind() == reflect.Map {
+		return b.bindMap(v, current, path)
+
+	} else if t.Kind() == reflect.Interface {
+		return b.bindFlexible(v, t, path)
+	}
+	return reflect.Value{}, b.fail(path, errors.Errorf("no setter for type '%s/%v'", t, t.Kind()))
+}
+
+func (b *binder) bindUnmarshaler(v interface{}, t reflect.Type, path string) (reflect.Value, error) {
+	elem := reflect.New(valueFromPtr(t))
+	if u, ok := elem.Interface().(CfUnmarshaler); ok {
+		if err := u.UnmarshalCf(v, b.opt.at(path)); err != nil {
+			return reflect.Value{}, b.fail(path, err)
+		}
+	} else if u, ok := elem.Interface().(encoding.TextUnmarshaler); ok {
+		expanded, err := b.expandVariables(v)
+		if err != nil {
+			return reflect.Value{}, b.fail(path, err)
+		}
+		var text string
+		switch vt := expanded.(type) {
+		case string:
+			text = vt
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			text = fmt.Sprintf("%v", vt)
+		default:
+			return reflect.Value{}, b.fail(path, errors.Errorf("got [%s], expected text for [%s]", reflect.TypeOf(expanded), t))
+		}
+		if err := u.UnmarshalText([]byte(text)); err != nil {
+			return reflect.Value{}, b.fail(path, err)
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		return elem, nil
+	}
+	return elem.Elem(), nil
+}
+
+func (b *binder) bindSlice(v interface{}, current reflect.Value, path string) (reflect.Value, error) {
+	t := current.Type()
+	if reflect.ValueOf(v).Kind() != reflect.Slice { // the data should also be a slice
+		return reflect.Value{}, b.fail(path, errors.Errorf("invalid array data (%v)", reflect.TypeOf(v)))
+	}
+	out := reflect.Zero(t)
+	if current.IsValid() {
+		out = current
+	}
+	elemType := t.Elem()
+	for i := 0; i < reflect.ValueOf(v).Len(); i++ { // iterate over the available data
+		sliceV := reflect.ValueOf(v).Index(i).Interface()
+		value, err := b.bindValue(sliceV, reflect.New(elemType).Elem(), indexPath(path, i))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if value.IsValid() {
+			out = reflect.Append(out, value)
+		}
+	}
+	return out, nil
+}
+
+func (b *binder) bindMap(v interface{}, current reflect.Value, path string) (reflect.Value, error) {
+	t := current.Type()
+	if t.Key().Kind() != reflect.String {
+		return reflect.Value{}, b.fail(path, errors.Errorf("unsupported map key type '%s'", t.Key()))
+	}
+	if vt, ok := v.(map[interface{}]interface{}); ok {
+		v = MapIToMapS(vt)
+	}
+	subData, ok := v.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, b.fail(path, errors.Errorf("invalid map data (%v)", reflect.TypeOf(v)))
+	}
+	out := current
+	if !current.IsValid() || current.IsNil() {
+		out = reflect.MakeMapWithSize(t, len(subData))
+	}
+	// in key order, so that aggregated errors are reported in a stable order
+	keys := make([]string, 0, len(subData))
+	for k := range subData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kv := subData[k]
+		if !b.opt.resolved && inlineVariablesFound(k) {
+			key, err := expandString(k, b.opt, nil)
+			if err != nil {
+				if err := b.fail(joinPath(path, k), err); err != nil {
+					return reflect.Value{}, err
 				}
+				continue
+			}
+			k = key
+		}
+		value, err := b.bindValue(kv, reflect.New(t.Elem()).Elem(), joinPath(path, k))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if value.IsValid() {
+			out.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), value)
+		}
+	}
+	return out, nil
+}
+
+func (b *binder) bindFlexible(v interface{}, t reflect.Type, path string) (reflect.Value, error) {
+	subData, ok := v.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, b.fail(path, errors.New("interface{} value requires sub data map"))
+	}
+	if !b.opt.resolved {
+		// flexible setters receive the data with every variable expanded
+		resolved, err := expandData(subData, path, b.opt)
+		if err != nil {
+			if be, ok := err.(*BindError); ok {
+				return reflect.Value{}, b.fail(be.Path, be.Err)
 			}
+			return reflect.Value{}, b.fail(path, err)
 		}
+		subData = resolved.(map[string]interface{})
+	}
+	typeV, ok := subData["type"]
+	if !ok {
+		return reflect.Value{}, b.fail(path, errors.New("no 'type' data found"))
+	}
+	typeName, ok := typeV.(string)
+	if !ok {
+		return reflect.Value{}, b.fail(path, errors.New("'type' data not string value"))
+	}
+	fs, found := b.opt.FlexibleSetters[typeName]
+	if !found {
+		return reflect.Value{}, b.fail(path, errors.Errorf("no flexible setter with type '%s'", typeName))
+	}
+	fopt := b.opt.at(path)
+	fopt.flexible = true
+	fopt.resolved = true
+	value, err := fs(subData, fopt)
+	if err != nil {
+		return reflect.Value{}, b.fail(path, errors.Wrapf(err, "flexible setter error for type '%s'", typeName))
+	}
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	return reflect.ValueOf(value), nil
+}
+
+// expandVariables expands the variables in v, unless the data being bound has already been resolved.
+func (b *binder) expandVariables(v interface{}) (interface{}, error) {
+	if b.opt.resolved {
+		return v, nil
+	}
+	return expandVariables(v, b.opt)
+}
+
+// fail records a binding error at path. The returned error is non-nil when binding should stop at the first error.
+func (b *binder) fail(path string, err error) error {
+	var nested BindErrors
+	if errors.As(err, &nested) {
+		b.errs = append(b.errs, nested...)
+	} else {
+		b.errs = append(b.errs, &BindError{Path: path, Err: err})
+	}
+	if b.opt.AggregateErrors {
+		return nil
+	}
+	return b.errs
+}
+
+// valueBinder returns a binder for checking a single value, which stops at the first failure whatever
+// opt.AggregateErrors says.
+func valueBinder(opt *Options) *binder {
+	vopt := *opt
+	vopt.AggregateErrors = false
+	return &binder{opt: &vopt}
+}
+
+func (b *binder) result() error {
+	if len(b.errs) > 0 {
+		return b.errs
 	}
 	return nil
 }
 
 type fieldData struct {
-	name     string
-	skip     bool
-	required bool
-	secret   bool
+	name         string
+	skip         bool
+	required     bool
+	secret       bool
+	hasDefault   bool
+	defaultValue string
+	env          string
+	rules        []Rule
 }
 
 func parseFieldData(v reflect.StructField, opt *Options) fieldData {
 	fd := fieldData{name: opt.NameConverter(v), skip: false, required: false}
 	data := v.Tag.Get("cf")
 	if data != "" {
-		tokens := strings.Split(data, ",")
-		for _, token := range tokens {
+		for _, token := range tagTokens(data) {
 			if token == "+required" {
 				fd.required = true
 			} else if token == "+skip" {
 				fd.skip = true
 			} else if token == "+secret" {
 				fd.secret = true
+			} else if strings.HasPrefix(token, "+default=") {
+				fd.hasDefault = true
+				fd.defaultValue = strings.TrimPrefix(token, "+default=")
+			} else if strings.HasPrefix(token, "+env=") {
+				fd.env = strings.TrimPrefix(token, "+env=")
+			} else if r, ok := parseRule(token); ok {
+				fd.rules = append(fd.rules, r)
 			} else {
 				fd.name = token
 			}
@@ -230,6 +523,74 @@ func parseFieldData(v reflect.StructField, opt *Options) fieldData {
 	return fd
 }
 
+// tagTokens splits a cf tag into its comma-separated tokens. The values of "+default=" and "+pattern=" may contain
+// commas: any following tokens up to the next one starting with "+" are part of the value, so that
+// `cf:"+pattern=^[a-z]{2,3}$"` and `cf:"+default=a,b"` work as expected. A field name must therefore precede such a
+// token.
+func tagTokens(tag string) []string {
+	var tokens []string
+	continued := false
+	for _, token := range strings.Split(tag, ",") {
+		if continued && !strings.HasPrefix(token, "+") {
+			tokens[len(tokens)-1] += "," + token
+			continue
+		}
+		tokens = append(tokens, token)
+		continued = strings.HasPrefix(token, "+default=") || strings.HasPrefix(token, "+pattern=")
+	}
+	return tokens
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// hasDefaults reports whether t is a structure (by value, and not bound by a setter or unmarshaler) with a '+default'
+// tag on any of its fields, or on the fields of its nested structures.
+func hasDefaults(t reflect.Type, opt *Options) bool {
+	return structHasDefaults(t, opt, map[reflect.Type]bool{})
+}
+
+func structHasDefaults(t reflect.Type, opt *Options, seen map[reflect.Type]bool) bool {
+	if t.Kind() != reflect.Struct || seen[t] || isUnmarshaler(t) {
+		return false
+	}
+	if _, found := opt.Setters[t]; found {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+		fd := parseFieldData(t.Field(i), opt)
+		if !fd.skip && (fd.hasDefault || structHasDefaults(t.Field(i).Type, opt, seen)) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultData converts the text of a '+default=' tag into data for a field of type t. String fields receive the text
+// as-is; anything else receives the text parsed as a yaml scalar, so that "30" binds as an int and "true" as a bool.
+func defaultData(text string, t reflect.Type) interface{} {
+	if valueFromPtr(t).Kind() == reflect.String {
+		return text
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil || v == nil {
+		return text
+	}
+	return v
+}
+
 func valueFromPtr(t reflect.Type) reflect.Type {
 	if t.Kind() == reflect.Ptr {
 		return t.Elem()
@@ -17,9 +17,14 @@
 package cf
 
 import (
+	"fmt"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -467,3 +472,384 @@ func TestFloatWithIntData(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, float64(56.0), basic.FloatValue)
 }
+
+func TestMapOfStructPtr(t *testing.T) {
+	root := &struct {
+		Listeners map[string]*nestedType
+	}{}
+
+	var data = map[string]interface{}{
+		"listeners": map[string]interface{}{
+			"a": map[string]interface{}{"name": "oh"},
+			"b": map[string]interface{}{"name": "wow", "count": 2},
+		},
+	}
+
+	opt := DefaultOptions().AddInstantiator(reflect.TypeOf(nestedType{}), func() interface{} { return newNestedType() })
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(root.Listeners))
+	assert.Equal(t, "oh", root.Listeners["a"].Name)
+	assert.Equal(t, 33, root.Listeners["a"].Count)
+	assert.Equal(t, "wow", root.Listeners["b"].Name)
+	assert.Equal(t, 2, root.Listeners["b"].Count)
+}
+
+func TestMapOfScalars(t *testing.T) {
+	root := &struct {
+		Timeouts map[string]time.Duration
+		Names    map[string]string
+	}{}
+
+	var data = map[string]interface{}{
+		"timeouts": map[string]interface{}{
+			"connect": "5s",
+			"idle":    "1m",
+		},
+		"names": map[interface{}]interface{}{
+			"a": "${a}",
+		},
+	}
+
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		if vname == "a" {
+			return "oh, wow!", true
+		}
+		return nil, false
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, root.Timeouts["connect"])
+	assert.Equal(t, time.Minute, root.Timeouts["idle"])
+	assert.Equal(t, "oh, wow!", root.Names["a"])
+
+	data = map[string]interface{}{
+		"timeouts": map[string]interface{}{
+			"connect": 5,
+		},
+	}
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+}
+
+func TestMapOfFlexible(t *testing.T) {
+	root := &struct {
+		Flexibles map[string]interface{}
+	}{}
+
+	var data = map[string]interface{}{
+		"flexibles": map[string]interface{}{
+			"first": map[string]interface{}{
+				"type": "a",
+			},
+		},
+	}
+
+	opt := DefaultOptions()
+	opt = opt.AddFlexibleSetter("a", func(v interface{}, opt *Options) (interface{}, error) { return &flexibleType{"a"}, nil })
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(root.Flexibles))
+	assert.Equal(t, "a", root.Flexibles["first"].(*flexibleType).value)
+}
+
+func TestStrict(t *testing.T) {
+	root := &struct {
+		ListenAddress string
+		Nesteds       []*nestedType
+		Flexible      interface{}
+	}{}
+
+	var data = map[string]interface{}{
+		"lisen_address": "0.0.0.0:1280",
+		"nesteds": []interface{}{
+			map[string]interface{}{"name": "a", "cuont": 1},
+		},
+		"flexible": map[string]interface{}{
+			"type":  "a",
+			"valeu": "oh, wow!",
+		},
+	}
+
+	opt := DefaultOptions()
+	opt.AddFlexibleSetter("a", func(v interface{}, opt *Options) (interface{}, error) {
+		flexible := &struct{ Value string }{}
+		if err := Bind(flexible, v.(map[string]interface{}), opt); err != nil {
+			return nil, err
+		}
+		return flexible, nil
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+
+	var warnings []string
+	opt.SetUnknownKeyHandler(func(path string) { warnings = append(warnings, path) })
+	err = Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nesteds[0].cuont", "flexible.valeu", "lisen_address"}, warnings)
+
+	opt.UnknownKeyHandler = nil
+	opt.Strict = true
+	opt.AggregateErrors = true
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, len(err.(BindErrors)))
+	assert.Equal(t, "nesteds[0].cuont", err.(BindErrors)[0].Path)
+}
+
+func TestStrictSkip(t *testing.T) {
+	root := &struct {
+		Name     string
+		Internal string `cf:"+skip"`
+	}{}
+
+	data := map[string]interface{}{"name": "a", "internal": "b"}
+
+	opt := DefaultOptions()
+	opt.Strict = true
+	err := Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "internal", err.(BindErrors)[0].Path)
+	assert.Equal(t, "", root.Internal)
+}
+
+func TestDefaults(t *testing.T) {
+	type listener struct {
+		Address string `cf:"+default=0.0.0.0:1280"`
+		Enabled bool   `cf:"+default=true"`
+	}
+	root := &struct {
+		Count     int           `cf:"+default=33"`
+		Timeout   time.Duration `cf:"+default=30s"`
+		DataPath  string        `cf:"data_dir,+default=${home}/data"`
+		Ratio     float64       `cf:"+default=0.5"`
+		Listeners []*listener
+	}{}
+
+	var data = map[string]interface{}{
+		"ratio": 0.75,
+		"listeners": []interface{}{
+			map[string]interface{}{"enabled": false},
+		},
+	}
+
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		if vname == "home" {
+			return "/home/cf", true
+		}
+		return nil, false
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 33, root.Count)
+	assert.Equal(t, 30*time.Second, root.Timeout)
+	assert.Equal(t, "/home/cf/data", root.DataPath)
+	assert.Equal(t, 0.75, root.Ratio)
+	assert.Equal(t, 1, len(root.Listeners))
+	assert.Equal(t, "0.0.0.0:1280", root.Listeners[0].Address)
+	assert.False(t, root.Listeners[0].Enabled)
+}
+
+func TestDefaultsAbsentStruct(t *testing.T) {
+	type tls struct {
+		Enabled bool `cf:"+default=true"`
+	}
+	type server struct {
+		Port int `cf:"+default=80"`
+		Tls  tls
+	}
+	root := &struct {
+		Server   server
+		Optional *server
+	}{}
+
+	err := Bind(root, map[string]interface{}{}, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, 80, root.Server.Port)
+	assert.True(t, root.Server.Tls.Enabled)
+	assert.Nil(t, root.Optional)
+
+	root.Server = server{}
+	err = Bind(root, map[string]interface{}{"server": map[string]interface{}{}}, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, 80, root.Server.Port)
+}
+
+func TestPtrToScalar(t *testing.T) {
+	root := &struct {
+		Count    *int
+		Enabled  *bool
+		Timeout  *time.Duration
+		Optional *string
+		Retries  *int `cf:"+default=3"`
+		Names    []*string
+	}{}
+
+	var data = map[string]interface{}{
+		"count":    0,
+		"enabled":  false,
+		"timeout":  "30s",
+		"optional": nil,
+		"names":    []interface{}{"a", "b"},
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+	assert.NotNil(t, root.Count)
+	assert.Equal(t, 0, *root.Count)
+	assert.NotNil(t, root.Enabled)
+	assert.False(t, *root.Enabled)
+	assert.Equal(t, 30*time.Second, *root.Timeout)
+	assert.Nil(t, root.Optional)
+	assert.Equal(t, 3, *root.Retries)
+	assert.Equal(t, 2, len(root.Names))
+	assert.Equal(t, "b", *root.Names[1])
+}
+
+type unmarshalId struct {
+	prefix string
+	id     int
+}
+
+func (u *unmarshalId) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), "-")
+	if len(parts) != 2 {
+		return errors.Errorf("invalid id '%s'", text)
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	u.prefix = parts[0]
+	u.id = id
+	return nil
+}
+
+type unmarshalEndpoint struct {
+	Host string
+	Port int
+}
+
+func (u *unmarshalEndpoint) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), ":")
+	if len(parts) != 2 {
+		return errors.Errorf("invalid endpoint '%s'", text)
+	}
+	port, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	u.Host = parts[0]
+	u.Port = port
+	return nil
+}
+
+type unmarshalPair struct {
+	left  string
+	right string
+	opt   *Options
+}
+
+func (u *unmarshalPair) UnmarshalCf(v interface{}, opt *Options) error {
+	if vt, ok := v.([]interface{}); ok && len(vt) == 2 {
+		u.left = fmt.Sprintf("%v", vt[0])
+		u.right = fmt.Sprintf("%v", vt[1])
+		u.opt = opt
+		return nil
+	}
+	return errors.Errorf("expected pair, got [%v]", v)
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	root := &struct {
+		Address net.IP
+		Big     *big.Int
+		Id      unmarshalId
+		Ids     []*unmarshalId
+	}{}
+
+	var data = map[string]interface{}{
+		"address": "10.0.0.1",
+		"big":     "12345678901234567890",
+		"id":      "router-${n}",
+		"ids":     []interface{}{"a-1", "b-2"},
+	}
+
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		if vname == "n" {
+			return "7", true
+		}
+		return nil, false
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", root.Address.String())
+	assert.Equal(t, "12345678901234567890", root.Big.String())
+	assert.Equal(t, unmarshalId{"router", 7}, root.Id)
+	assert.Equal(t, 2, len(root.Ids))
+	assert.Equal(t, 2, root.Ids[1].id)
+
+	data = map[string]interface{}{
+		"id": "router",
+	}
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "id: invalid id 'router'", err.Error())
+}
+
+func TestTextUnmarshalerStructData(t *testing.T) {
+	root := &struct {
+		Short unmarshalEndpoint
+		Long  *unmarshalEndpoint
+	}{}
+
+	var data = map[string]interface{}{
+		"short": "localhost:1280",
+		"long": map[string]interface{}{
+			"host": "example.com",
+			"port": 443,
+		},
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, unmarshalEndpoint{"localhost", 1280}, root.Short)
+	assert.Equal(t, unmarshalEndpoint{"example.com", 443}, *root.Long)
+}
+
+func TestCfUnmarshaler(t *testing.T) {
+	root := &struct {
+		Pair  unmarshalPair
+		Pairs map[string]*unmarshalPair
+	}{}
+
+	var data = map[string]interface{}{
+		"pair": []interface{}{"a", 1},
+		"pairs": map[string]interface{}{
+			"x": []interface{}{"b", true},
+		},
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "a", root.Pair.left)
+	assert.Equal(t, "1", root.Pair.right)
+	assert.NotNil(t, root.Pair.opt)
+	assert.Equal(t, "true", root.Pairs["x"].right)
+
+	data = map[string]interface{}{
+		"pair": "a",
+	}
+	err = Bind(root, data, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "pair: expected pair, got [a]", err.Error())
+}
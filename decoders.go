@@ -0,0 +1,175 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"math"
+	"path/filepath"
+)
+
+// YamlDecoder decodes yaml, resolving includes (see IncludeKey) relative to path.
+func YamlDecoder(path string, data []byte) (map[string]interface{}, error) {
+	dataMap, _, err := decodeYaml(path, data)
+	return dataMap, err
+}
+
+// YamlPositionalDecoder decodes yaml like YamlDecoder, also returning the file, line and column of every value.
+func YamlPositionalDecoder(path string, data []byte) (map[string]interface{}, Origins, error) {
+	return decodeYaml(path, data)
+}
+
+// decodeYaml decodes yaml data like YamlDecoder, also returning the origin (file, line and column) of every value in
+// the data, keyed by path. The origin of a value within a mapping is the position of its key.
+func decodeYaml(path string, data []byte) (map[string]interface{}, Origins, error) {
+	v, origins, err := decodeYamlFragment(path, data, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v == nil {
+		return make(map[string]interface{}), origins, nil
+	}
+	dataMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.Errorf("error parsing yaml [%s]: top-level value is not a map", path)
+	}
+	// discard the origins of values overridden while resolving includes
+	for originPath := range origins {
+		if _, found := lookupPath(dataMap, originPath); !found {
+			delete(origins, originPath)
+		}
+	}
+	return dataMap, origins, nil
+}
+
+// decodeYamlFragment decodes a yaml document of any shape, resolving its includes. The stack holds the absolute paths
+// of the files including this one.
+func decodeYamlFragment(path string, data []byte, stack []string) (interface{}, Origins, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, errors.Wrapf(err, "error parsing yaml [%s]", path)
+	}
+	origins := make(Origins)
+	if len(root.Content) == 0 {
+		return nil, origins, nil
+	}
+	var v interface{}
+	if err := root.Decode(&v); err != nil {
+		return nil, nil, errors.Wrapf(err, "error parsing yaml [%s]", path)
+	}
+	yamlOrigins(root.Content[0], path, "", origins)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error resolving [%s]", path)
+	}
+	inc := &includer{dir: filepath.Dir(path), origins: origins, stack: append(stack, absPath)}
+	v, err = inc.resolve(CleanUpMapValue(v), "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, origins, nil
+}
+
+func yamlOrigins(n *yaml.Node, file, path string, origins Origins) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		yamlOrigins(n.Alias, file, path, origins)
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Tag == "!!merge" {
+				// merged keys originate from the anchored mapping(s)
+				if v.Kind == yaml.SequenceNode {
+					for _, merged := range v.Content {
+						yamlOrigins(merged, file, path, origins)
+					}
+				} else {
+					yamlOrigins(v, file, path, origins)
+				}
+				continue
+			}
+			childPath := joinPath(path, k.Value)
+			origins[childPath] = Origin{Kind: "file", Name: file, Line: k.Line, Column: k.Column}
+			yamlOrigins(v, file, childPath, origins)
+		}
+
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			childPath := indexPath(path, i)
+			origins[childPath] = Origin{Kind: "file", Name: file, Line: c.Line, Column: c.Column}
+			yamlOrigins(c, file, childPath, origins)
+		}
+	}
+}
+
+// JsonDecoder decodes json, converting numbers into int (or int64/uint64, when out of the range of int) when they are
+// integral, and float64 otherwise.
+func JsonDecoder(path string, data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	dataMap := make(map[string]interface{})
+	if err := decoder.Decode(&dataMap); err != nil {
+		return nil, errors.Wrapf(err, "error parsing json [%s]", path)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.Errorf("error parsing json [%s]: unexpected data after top-level object", path)
+	}
+	for k, v := range dataMap {
+		dataMap[k] = jsonNumbers(v)
+	}
+	return dataMap, nil
+}
+
+func jsonNumbers(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		for k, kv := range vt {
+			vt[k] = jsonNumbers(kv)
+		}
+		return vt
+
+	case []interface{}:
+		for i, iv := range vt {
+			vt[i] = jsonNumbers(iv)
+		}
+		return vt
+
+	case json.Number:
+		if i, err := vt.Int64(); err == nil {
+			if i >= math.MinInt && i <= math.MaxInt {
+				return int(i)
+			}
+			return i
+		}
+		var u uint64
+		if err := json.Unmarshal([]byte(vt), &u); err == nil {
+			return u
+		}
+		if f, err := vt.Float64(); err == nil {
+			return f
+		}
+		return vt.String()
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,136 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodersCf struct {
+	Name    string
+	Port    uint16
+	Ratio   float64
+	Timeout time.Duration
+	Big     int64
+	Tags    []string
+}
+
+func writeTestFile(t *testing.T, name, data string) string {
+	return filepath.Join(writeTestFiles(t, map[string]string{name: data}), name)
+}
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBindJson(t *testing.T) {
+	path := writeTestFile(t, "cf.json", `{"name": "oh, wow!", "port": 8080, "ratio": 0.5, "timeout": "30s", "big": 9007199254740993, "tags": ["a", "b"]}`)
+
+	cf := &decodersCf{}
+	err := BindJson(cf, path, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "oh, wow!", cf.Name)
+	assert.Equal(t, uint16(8080), cf.Port)
+	assert.Equal(t, 0.5, cf.Ratio)
+	assert.Equal(t, 30*time.Second, cf.Timeout)
+	assert.Equal(t, int64(9007199254740993), cf.Big)
+	assert.Equal(t, []string{"a", "b"}, cf.Tags)
+}
+
+func TestJsonDecoderNumbers(t *testing.T) {
+	data, err := JsonDecoder("test.json", []byte(`{"a": 1, "b": 1.5, "c": 18446744073709551615, "d": [2]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, data["a"])
+	assert.Equal(t, 1.5, data["b"])
+	assert.Equal(t, uint64(18446744073709551615), data["c"])
+	assert.Equal(t, []interface{}{2}, data["d"])
+
+	_, err = JsonDecoder("test.json", []byte(`{"a": 1} {"b": 2}`))
+	assert.NotNil(t, err)
+}
+
+func TestBindFile(t *testing.T) {
+	cf := &decodersCf{}
+	err := BindFile(cf, writeTestFile(t, "cf.yml", "name: yaml\nport: 1280\n"), DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "yaml", cf.Name)
+	assert.Equal(t, uint16(1280), cf.Port)
+
+	err = BindFile(cf, writeTestFile(t, "cf.JSON", `{"name": "json"}`), DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "json", cf.Name)
+
+	path := writeTestFile(t, "cf.props", "name=props\nport=1281\n")
+	err = BindFile(cf, path, DefaultOptions())
+	assert.NotNil(t, err)
+
+	opt := DefaultOptions().AddDecoder(".props", func(path string, data []byte) (map[string]interface{}, error) {
+		dataMap := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			dataMap[kv[0]] = kv[1]
+		}
+		return dataMap, nil
+	})
+	err = BindFile(cf, path, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "props", cf.Name)
+	assert.Equal(t, uint16(1281), cf.Port)
+}
+
+func TestPositionalDecoder(t *testing.T) {
+	path := writeTestFile(t, "cf.conf", "name: conf\nport: nope\n")
+	opt := DefaultOptions().AddPositionalDecoder(".conf", YamlPositionalDecoder)
+
+	err := BindFile(&decodersCf{}, path, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, err.(BindErrors)[0].Origin.Line)
+
+	l := NewLoader(FileSource(path))
+	err = l.Load(&decodersCf{}, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, err.(BindErrors)[0].Origin.Line)
+
+	l = NewLoader(FileSource(writeTestFile(t, "cf.yml", "name: yml\nport: 1280\n")))
+	assert.Nil(t, l.Load(&decodersCf{}, DefaultOptions()))
+	origin, _ := l.Origin("port")
+	assert.Equal(t, 2, origin.Line)
+
+	// a plain decoder replaces the positional decoder for its extension
+	opt.AddDecoder(".conf", func(string, []byte) (map[string]interface{}, error) {
+		return map[string]interface{}{"port": "nope"}, nil
+	})
+	err = BindFile(&decodersCf{}, path, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, err.(BindErrors)[0].Origin.Line)
+}
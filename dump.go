@@ -17,82 +17,274 @@
 package cf
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 )
 
+// DumpOptions controls the layout of Dump and DumpTo output. The zero value produces the default layout.
+type DumpOptions struct {
+	// IndentSpaces indents each level with this many spaces, rather than a tab.
+	IndentSpaces int
+
+	// MaxDepth elides structures nested more than MaxDepth levels deep, when greater than 0.
+	MaxDepth int
+
+	// HideZero leaves out fields holding the zero value of their type.
+	HideZero bool
+
+	// SortFields orders fields by name, rather than by declaration.
+	SortFields bool
+
+	// SecretMask selects how secret values are shown, by Dump as well as DumpYaml and DumpJson.
+	SecretMask SecretMask
+
+	// FingerprintKey keys the fingerprints shown by MaskFingerprint.
+	FingerprintKey []byte
+}
+
 func Dump(cf interface{}, opt *Options) string {
-	return dump(reflect.ValueOf(cf), -1, opt)
+	out := new(strings.Builder)
+	_ = DumpTo(out, cf, opt)
+	return out.String()
+}
+
+// DumpOrigins dumps cf like Dump, annotating each value with its origin (see Loader.Origins).
+func DumpOrigins(cf interface{}, origins Origins, opt *Options) string {
+	out := new(strings.Builder)
+	_ = newDumper(out, origins, opt).dumpRoot(cf)
+	return out.String()
+}
+
+// DumpTo writes the dump of cf to w, laid out according to Options.DumpOptions.
+func DumpTo(w io.Writer, cf interface{}, opt *Options) error {
+	bw := bufio.NewWriter(w)
+	if err := newDumper(bw, nil, opt).dumpRoot(cf); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DumpYaml dumps cf as a yaml document that Bind would accept, with secret values ('+secret' fields and Secret values)
+// masked according to DumpOptions.SecretMask. Values are converted as by Unbind, so durations appear as "30s".
+func DumpYaml(cf interface{}, opt *Options) (string, error) {
+	data, err := unbind(cf, opt, true)
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// DumpJson dumps cf as an indented json document, like DumpYaml.
+func DumpJson(cf interface{}, opt *Options) (string, error) {
+	data, err := unbind(cf, opt, true)
+	if err != nil {
+		return "", err
+	}
+	out := new(strings.Builder)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false) // keep placeholders such as "<SECRET>" readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// dumper holds the state of a single Dump.
+type dumper struct {
+	w       io.Writer
+	err     error
+	opt     *Options
+	origins Origins
+	visited map[pointerVisit]string // pointers being dumped, with their paths, for cycle detection
+}
+
+func newDumper(w io.Writer, origins Origins, opt *Options) *dumper {
+	return &dumper{w: w, opt: opt, origins: origins, visited: make(map[pointerVisit]string)}
+}
+
+func (d *dumper) dumpRoot(cf interface{}) error {
+	d.dump(reflect.ValueOf(cf), -1, "")
+	return d.err
+}
+
+// write writes s, unless an earlier write failed.
+func (d *dumper) write(s ...string) {
+	for _, str := range s {
+		if d.err != nil {
+			return
+		}
+		_, d.err = io.WriteString(d.w, str)
+	}
+}
+
+func (d *dumper) indent(n int) string {
+	if d.opt.DumpOptions.IndentSpaces > 0 {
+		return strings.Repeat(" ", n*d.opt.DumpOptions.IndentSpaces)
+	}
+	return strings.Repeat("\t", n)
+}
+
+// elided reports whether structures at indent are beyond DumpOptions.MaxDepth.
+func (d *dumper) elided(indent int) bool {
+	return d.opt.DumpOptions.MaxDepth > 0 && indent >= d.opt.DumpOptions.MaxDepth
 }
 
-func dump(v reflect.Value, indent int, opt *Options) string {
+func (d *dumper) dump(v reflect.Value, indent int, path string) {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		visit := pointerVisit{v.Type(), v.Pointer()}
+		if at, found := d.visited[visit]; found {
+			if at == "" {
+				at = "<root>"
+			}
+			d.write("<cycle: ", at, ">")
+			return
+		}
+		d.visited[visit] = path
+		defer delete(d.visited, visit)
+	}
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	switch v.Kind() {
-	case reflect.Struct:
-		return dumpStruct(v, indent+1, opt)
-	case reflect.Slice:
-		return dumpSlice(v, indent+1, opt)
-	default:
-		return dumpValue(v)
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		// flexible value; show the concrete type
+		d.write(v.Elem().Type().String(), " ")
+		d.dump(v.Elem(), indent, path)
+		return
+	}
+	if v.IsValid() && v.Type() == secretType {
+		d.write(maskSecret(v, d.opt))
+		return
+	}
+	if v.IsValid() {
+		if _, ok := textMarshaler(v); !ok {
+			switch v.Kind() {
+			case reflect.Struct:
+				d.dumpStruct(v, indent+1, path)
+				return
+			case reflect.Slice:
+				d.dumpSlice(v, indent+1, path)
+				return
+			case reflect.Map:
+				d.dumpMap(v, indent+1, path)
+				return
+			}
+		}
+	}
+	d.write(dumpValue(v))
+	if origin, found := d.origins[path]; found {
+		d.write(" # ", origin.String())
 	}
 }
 
-func dumpStruct(v reflect.Value, indent int, opt *Options) string {
-	format := fmt.Sprintf("%%-%ds", maxFieldNameLength(v, opt))
-	out := "{\n"
+type dumpField struct {
+	fd    fieldData
+	value reflect.Value
+}
+
+func (d *dumper) dumpStruct(v reflect.Value, indent int, path string) {
+	if d.elided(indent) {
+		d.write("{...}")
+		return
+	}
+	var fields []dumpField
+	maxFieldNameLength := 0
 	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).CanInterface() {
-			fd := parseFieldData(v.Type().Field(i), opt)
-			if !fd.secret {
-				out += nTabs(indent+1) + fmt.Sprintf(format, fd.name) + " = " + dump(v.Field(i), indent, opt) + "\n"
-			} else {
-				out += nTabs(indent+1) + fmt.Sprintf(format, fd.name) + " = <SECRET>\n"
-			}
+		fd := parseFieldData(v.Type().Field(i), d.opt)
+		hidden := d.opt.DumpOptions.HideZero && v.Field(i).IsZero()
+		if v.Field(i).CanInterface() && !hidden {
+			fields = append(fields, dumpField{fd, v.Field(i)})
+		}
+		if !hidden && len(fd.name) > maxFieldNameLength {
+			// aligned with every field, unless hiding zero values
+			maxFieldNameLength = len(fd.name)
+		}
+	}
+	if d.opt.DumpOptions.SortFields {
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].fd.name < fields[j].fd.name })
+	}
+	format := fmt.Sprintf("%%-%ds", maxFieldNameLength)
+	d.write("{\n")
+	for _, f := range fields {
+		d.write(d.indent(indent+1), fmt.Sprintf(format, f.fd.name), " = ")
+		if !f.fd.secret {
+			d.dump(f.value, indent, joinPath(path, f.fd.name))
+		} else {
+			d.write(maskSecret(f.value, d.opt))
 		}
+		d.write("\n")
 	}
-	out += nTabs(indent) + "}"
-	return out
+	d.write(d.indent(indent), "}")
 }
 
-func dumpSlice(v reflect.Value, indent int, opt *Options) string {
-	out := "[\n"
+func (d *dumper) dumpSlice(v reflect.Value, indent int, path string) {
+	if d.elided(indent) {
+		d.write("[...]")
+		return
+	}
+	d.write("[\n")
 	for i := 0; i < v.Len(); i++ {
-		out += nTabs(indent+1) + dump(v.Index(i), indent, opt) + "\n"
+		d.write(d.indent(indent + 1))
+		d.dump(v.Index(i), indent, indexPath(path, i))
+		d.write("\n")
 	}
-	out += nTabs(indent) + "]"
-	return out
+	d.write(d.indent(indent), "]")
 }
 
-func dumpValue(v reflect.Value) string {
-	if v.Kind() == reflect.String {
-		return fmt.Sprintf("\"%v\"", v.Interface())
-	} else {
-		if v.IsValid() {
-			return fmt.Sprintf("%v", v.Interface())
-		} else {
-			return "<nil>"
+func (d *dumper) dumpMap(v reflect.Value, indent int, path string) {
+	if d.elided(indent) {
+		d.write("{...}")
+		return
+	}
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	maxKeyLength := 0
+	for _, k := range v.MapKeys() {
+		key := fmt.Sprintf("%v", k.Interface())
+		keys = append(keys, key)
+		values[key] = v.MapIndex(k)
+		if len(key) > maxKeyLength {
+			maxKeyLength = len(key)
 		}
 	}
+	sort.Strings(keys)
+	format := fmt.Sprintf("%%-%ds", maxKeyLength)
+	d.write("{\n")
+	for _, key := range keys {
+		d.write(d.indent(indent+1), fmt.Sprintf(format, key), " = ")
+		d.dump(values[key], indent, joinPath(path, key))
+		d.write("\n")
+	}
+	d.write(d.indent(indent), "}")
 }
 
-func maxFieldNameLength(cfV reflect.Value, opt *Options) int {
-	maxFieldNameLength := 0
-	for i := 0; i < cfV.NumField(); i++ {
-		fd := parseFieldData(cfV.Type().Field(i), opt)
-		length := len(fd.name)
-		if length > maxFieldNameLength {
-			maxFieldNameLength = length
+func dumpValue(v reflect.Value) string {
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return "<nil>"
+	}
+	if tm, ok := textMarshaler(v); ok {
+		if text, err := tm.MarshalText(); err == nil {
+			return fmt.Sprintf("\"%s\"", text)
 		}
 	}
-	return maxFieldNameLength
-}
-
-func nTabs(n int) string {
-	out := ""
-	for i := 0; i < n; i++ {
-		out += "\t"
+	if v.Type() == durationType {
+		return v.Interface().(time.Duration).String()
 	}
-	return out
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("\"%v\"", v.Interface())
+	}
+	return fmt.Sprintf("%v", v.Interface())
 }
+
+var durationType = reflect.TypeOf(time.Duration(0))
@@ -18,7 +18,12 @@ package cf
 
 import (
 	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
 	"testing"
+	"time"
 )
 
 type dumpCfTiny struct {
@@ -71,3 +76,158 @@ func TestDumpSecret(t *testing.T) {
 	out := Dump(cf, DefaultOptions())
 	fmt.Println(out)
 }
+
+type dumpCfDocument struct {
+	Id      string
+	Key     string `cf:"+secret"`
+	Timeout time.Duration
+	Tinies  []*dumpCfTiny
+}
+
+func TestDumpYaml(t *testing.T) {
+	cf := &dumpCfDocument{Id: "id", Key: "s3c437", Timeout: 30 * time.Second, Tinies: []*dumpCfTiny{{"a"}}}
+	out, err := DumpYaml(cf, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "id: id\nkey: <SECRET>\ntimeout: 30s\ntinies:\n    - id: a\n", out)
+
+	opt := DefaultOptions()
+	opt.SecretPlaceholder = "REDACTED"
+	out, err = DumpJson(cf, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "id": "id",
+  "key": "REDACTED",
+  "timeout": "30s",
+  "tinies": [
+    {
+      "id": "a"
+    }
+  ]
+}`, out)
+	assert.Equal(t, "s3c437", cf.Key)
+}
+
+type dumpCfNode struct {
+	Name   string
+	Parent *dumpCfNode
+}
+
+func TestDumpValues(t *testing.T) {
+	cf := &struct {
+		Keys     map[string]dumpCfSecret
+		Flexible interface{}
+		Empty    interface{}
+		Timeout  time.Duration
+		Address  net.IP
+		Node     *dumpCfNode
+	}{
+		Keys: map[string]dumpCfSecret{
+			"second": {Id: "2", Key: "two"},
+			"first":  {Id: "1", Key: "one"},
+		},
+		Flexible: &dumpCfTiny{"flexible"},
+		Timeout:  90 * time.Second,
+		Address:  net.ParseIP("10.0.0.1"),
+		Node:     &dumpCfNode{Name: "node"},
+	}
+	cf.Node.Parent = cf.Node
+
+	out := Dump(cf, DefaultOptions())
+	expected := "{\n" +
+		"\tkeys     = {\n" +
+		"\t\tfirst  = {\n" +
+		"\t\t\tid  = \"1\"\n" +
+		"\t\t\tkey = <SECRET>\n" +
+		"\t\t}\n" +
+		"\t\tsecond = {\n" +
+		"\t\t\tid  = \"2\"\n" +
+		"\t\t\tkey = <SECRET>\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"\tflexible = *cf.dumpCfTiny {\n" +
+		"\t\tid = \"flexible\"\n" +
+		"\t}\n" +
+		"\tempty    = <nil>\n" +
+		"\ttimeout  = 1m30s\n" +
+		"\taddress  = \"10.0.0.1\"\n" +
+		"\tnode     = {\n" +
+		"\t\tname   = \"node\"\n" +
+		"\t\tparent = <cycle: node>\n" +
+		"\t}\n" +
+		"}"
+	assert.Equal(t, expected, out)
+}
+
+func TestDumpTo(t *testing.T) {
+	cf := &struct {
+		Name    string
+		Port    int
+		Address string
+		Tiny    dumpCfNested
+		Tinies  []*dumpCfTiny
+	}{Name: "a", Port: 1280, Tiny: dumpCfNested{Name: "nested", Tiny: dumpCfTiny{"x"}}, Tinies: []*dumpCfTiny{{"b"}}}
+
+	out := new(strings.Builder)
+	err := DumpTo(out, cf, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, Dump(cf, DefaultOptions()), out.String())
+
+	opt := DefaultOptions().SetDumpOptions(DumpOptions{IndentSpaces: 2, MaxDepth: 2, HideZero: true, SortFields: true})
+	out.Reset()
+	err = DumpTo(out, cf, opt)
+	assert.Nil(t, err)
+	expected := "{\n" +
+		"  name   = \"a\"\n" +
+		"  port   = 1280\n" +
+		"  tinies = [\n" +
+		"    {...}\n" +
+		"  ]\n" +
+		"  tiny   = {\n" +
+		"    name = \"nested\"\n" +
+		"    tiny = {...}\n" +
+		"  }\n" +
+		"}"
+	assert.Equal(t, expected, out.String())
+
+	err = DumpTo(failingWriter{}, cf, opt)
+	assert.NotNil(t, err)
+
+	cw := &countingWriter{}
+	err = DumpTo(cw, cf, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cw.writes) // buffered
+}
+
+type countingWriter struct {
+	writes int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.writes++
+	return len(p), nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestDumpFirstFieldPointer(t *testing.T) {
+	// a pointer to the first field shares the address of the struct, without being a cycle
+	cf := &struct {
+		Inner dumpCfTiny
+		P     *dumpCfTiny
+	}{Inner: dumpCfTiny{"inner"}}
+	cf.P = &cf.Inner
+
+	expected := "{\n" +
+		"\tinner = {\n" +
+		"\t\tid = \"inner\"\n" +
+		"\t}\n" +
+		"\tp     = {\n" +
+		"\t\tid = \"inner\"\n" +
+		"\t}\n" +
+		"}"
+	assert.Equal(t, expected, Dump(cf, DefaultOptions()))
+}
@@ -0,0 +1,177 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// OverlayEnv returns a copy of data, overlaid with values from environment variables named after the paths of the
+// fields of cf. The field at path "server.tls.cert_path" is overridden by the variable "<PREFIX>_SERVER_TLS_CERT_PATH";
+// slice elements are addressed by index, so "listeners[0].address" is overridden by "<PREFIX>_LISTENERS_0_ADDRESS". A
+// field tagged with "+env=NAME" is overridden by the variable NAME instead.
+//
+// Environment values are placed into the data as strings, and are checked against the setters for their fields, so
+// that a malformed value is reported against the name of its environment variable. Map and interface{} fields are not
+// overridden.
+func OverlayEnv(cf interface{}, data map[string]interface{}, prefix string, opt *Options) (map[string]interface{}, error) {
+	t := valueFromPtr(reflect.TypeOf(cf))
+	if t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("provided type [%s] is not a struct", t)
+	}
+	o := &envOverlay{env: environment(), opt: opt}
+	return o.overlayStruct(t, data, prefix, "")
+}
+
+type envOverlay struct {
+	env     map[string]string
+	opt     *Options
+	origins Origins
+}
+
+func (o *envOverlay) overlayStruct(t reflect.Type, data map[string]interface{}, prefix, path string) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" { // exported
+			fd := parseFieldData(t.Field(i), o.opt)
+			if !fd.skip {
+				name := envName(prefix, fd.name)
+				if fd.env != "" {
+					name = fd.env
+				}
+				v, found, err := o.overlayValue(t.Field(i).Type, out[fd.name], name, joinPath(path, fd.name))
+				if err != nil {
+					return nil, err
+				}
+				if found {
+					out[fd.name] = v
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// overlayValue overlays the environment onto the data v for a value of type t, returning the overlaid data and whether
+// the data exists at all.
+func (o *envOverlay) overlayValue(t reflect.Type, v interface{}, name, path string) (interface{}, bool, error) {
+	_, hasSetter := o.opt.Setters[t]
+	_, hasElemSetter := o.opt.Setters[valueFromPtr(t)]
+	vt := valueFromPtr(t)
+	switch {
+	case hasSetter || hasElemSetter || isUnmarshaler(t):
+		if ev, found := o.env[name]; found {
+			b := valueBinder(o.opt)
+			if _, err := b.bindValue(ev, reflect.New(t).Elem(), path); err != nil {
+				return nil, false, errors.Wrapf(err, "environment variable '%s'", name)
+			}
+			if o.origins != nil {
+				o.origins[path] = Origin{Kind: "env", Name: name}
+			}
+			return ev, true, nil
+		}
+
+	case vt.Kind() == reflect.Struct:
+		subData, _ := v.(map[string]interface{})
+		if subData == nil && !o.hasPrefix(name) {
+			break
+		}
+		out, err := o.overlayStruct(vt, subData, name, path)
+		if err != nil {
+			return nil, false, err
+		}
+		return out, true, nil
+
+	case t.Kind() == reflect.Slice:
+		var out []interface{}
+		if v != nil && reflect.ValueOf(v).Kind() == reflect.Slice {
+			for i := 0; i < reflect.ValueOf(v).Len(); i++ {
+				out = append(out, reflect.ValueOf(v).Index(i).Interface())
+			}
+		} else if v != nil || !o.hasPrefix(name) {
+			break
+		}
+		for i := 0; ; i++ {
+			elemName := envName(name, fmt.Sprintf("%d", i))
+			if i >= len(out) {
+				if _, found := o.env[elemName]; !found && !o.hasPrefix(elemName) {
+					break
+				}
+			}
+			var elemV interface{}
+			if i < len(out) {
+				elemV = out[i]
+			}
+			ev, found, err := o.overlayValue(t.Elem(), elemV, elemName, indexPath(path, i))
+			if err != nil {
+				return nil, false, err
+			}
+			if i < len(out) {
+				out[i] = ev
+			} else if found {
+				out = append(out, ev)
+			} else {
+				break
+			}
+		}
+		return out, true, nil
+	}
+	return v, v != nil, nil
+}
+
+// hasPrefix reports whether any environment variable is named with the prefix "<name>_".
+func (o *envOverlay) hasPrefix(name string) bool {
+	for k := range o.env {
+		if strings.HasPrefix(k, name+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func environment() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	return env
+}
+
+// envName appends the configuration name to the environment variable prefix, upper-casing it and replacing anything
+// other than letters and digits with underscores.
+func envName(prefix, name string) string {
+	converted := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+	if prefix == "" {
+		return converted
+	}
+	return prefix + "_" + converted
+}
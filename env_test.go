@@ -0,0 +1,81 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOverlayEnv(t *testing.T) {
+	t.Setenv("APP_NAME", "from env")
+	t.Setenv("DB_PASS", "s3cr3t")
+	t.Setenv("APP_SERVER_PORT", "8080")
+	t.Setenv("APP_SERVER_TIMEOUT", "5s")
+	t.Setenv("APP_SERVER_TLS_CERT_PATH", "/etc/cert.pem")
+	t.Setenv("APP_LISTENERS_1_ENABLED", "true")
+	t.Setenv("APP_LISTENERS_2_ADDRESS", "0.0.0.0:1282")
+	t.Setenv("APP_TAGS_0", "a")
+
+	data := map[string]interface{}{
+		"name": "from file",
+		"server": map[string]interface{}{
+			"port": 1280,
+		},
+		"listeners": []interface{}{
+			map[string]interface{}{"address": "0.0.0.0:1280"},
+			map[string]interface{}{"address": "0.0.0.0:1281"},
+		},
+	}
+
+	opt := DefaultOptions()
+	overlaid, err := OverlayEnv(&testRoot{}, data, "APP", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 1280, data["server"].(map[string]interface{})["port"]) // unmodified
+
+	root := &testRoot{}
+	err = Bind(root, overlaid, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "from env", root.Name)
+	assert.Equal(t, "s3cr3t", root.Password)
+	assert.Equal(t, uint16(8080), root.Server.Port)
+	assert.Equal(t, 5*time.Second, *root.Server.Timeout)
+	assert.Equal(t, "/etc/cert.pem", root.Server.Tls.CertPath)
+	assert.Equal(t, 3, len(root.Listeners))
+	assert.Equal(t, "0.0.0.0:1280", root.Listeners[0].Address)
+	assert.False(t, root.Listeners[0].Enabled)
+	assert.Equal(t, "0.0.0.0:1281", root.Listeners[1].Address)
+	assert.True(t, root.Listeners[1].Enabled)
+	assert.Equal(t, "0.0.0.0:1282", root.Listeners[2].Address)
+	assert.Equal(t, []string{"a"}, root.Tags)
+}
+
+func TestOverlayEnvInvalid(t *testing.T) {
+	t.Setenv("APP_SERVER_PORT", "70000")
+
+	_, err := OverlayEnv(&testRoot{}, map[string]interface{}{}, "APP", DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "environment variable 'APP_SERVER_PORT': server.port: value [70000] overflows [uint16]", err.Error())
+
+	t.Setenv("APP_SERVER_PORT", "oops")
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+	_, err = OverlayEnv(&testRoot{}, map[string]interface{}{}, "APP", opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "environment variable 'APP_SERVER_PORT': server.port: invalid value [oops] for [uint16]", err.Error())
+}
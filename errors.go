@@ -0,0 +1,92 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// BindError describes a single binding failure, located by the dotted path of the failing field within the
+// configuration data (for example "listeners[2].tls.cert_path").
+type BindError struct {
+	Path string
+	Err  error
+
+	// Origin locates the failing value within its source, when known (see BindYaml and Loader).
+	Origin Origin
+}
+
+func (e *BindError) Error() string {
+	out := e.Err.Error()
+	if e.Path != "" {
+		out = fmt.Sprintf("%s: %s", e.Path, out)
+	}
+	if e.Origin.Kind != "" {
+		out = fmt.Sprintf("%s: %s", e.Origin, out)
+	}
+	return out
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors is returned by Bind when binding fails. Unless Options.AggregateErrors is set, it contains only the first
+// failure.
+type BindErrors []*BindError
+
+func (errs BindErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	out := fmt.Sprintf("%d binding errors:", len(errs))
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return out + "\n\t" + strings.Join(msgs, "\n\t")
+}
+
+// locateErrors sets the origin of each BindError in err to the origin of its path, or of the closest enclosing path
+// (for a missing value), falling back to fallback.
+func locateErrors(err error, origins Origins, fallback Origin) error {
+	var errs BindErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			if e.Origin.Kind != "" {
+				continue
+			}
+			e.Origin = fallback
+			for path := e.Path; path != ""; path = parentPath(path) {
+				if origin, found := origins[path]; found {
+					e.Origin = origin
+					break
+				}
+			}
+		}
+	}
+	return err
+}
+
+func parentPath(path string) string {
+	if idx := strings.LastIndexAny(path, ".["); idx != -1 {
+		return path[:idx]
+	}
+	return ""
+}
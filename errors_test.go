@@ -0,0 +1,122 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func errorsData() map[string]interface{} {
+	return map[string]interface{}{
+		"timeout": 30,
+		"listeners": []interface{}{
+			map[string]interface{}{"address": "a"},
+			map[string]interface{}{"address": "b", "tls": map[string]interface{}{}},
+		},
+		"flexible": map[string]interface{}{
+			"type":  "nested",
+			"value": 33,
+		},
+	}
+}
+
+func errorsOptions() *Options {
+	return DefaultOptions().AddFlexibleSetter("nested", func(v interface{}, opt *Options) (interface{}, error) {
+		nested := &struct{ Value string }{}
+		if err := Bind(nested, v.(map[string]interface{}), opt); err != nil {
+			return nil, err
+		}
+		return nested, nil
+	})
+}
+
+func TestFirstBindError(t *testing.T) {
+	err := Bind(&testRoot{}, errorsData(), errorsOptions())
+	assert.NotNil(t, err)
+	var errs BindErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "name", errs[0].Path)
+}
+
+func TestAggregateBindErrors(t *testing.T) {
+	opt := errorsOptions()
+	opt.AggregateErrors = true
+
+	err := Bind(&testRoot{}, errorsData(), opt)
+	assert.NotNil(t, err)
+	var errs BindErrors
+	assert.True(t, errors.As(err, &errs))
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"name", "timeout", "listeners[1].tls.cert_path", "flexible.value"}, paths)
+	assert.Contains(t, err.Error(), "listeners[1].tls.cert_path: no data found for required field")
+}
+
+func TestBindYamlErrorPositions(t *testing.T) {
+	path := writeTestFile(t, "config.yml", `timeout: 30
+listeners:
+  - address: a
+  - address: b
+    tls:
+      cret_path: /etc/cert.pem
+`)
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+	opt.Strict = true
+
+	err := BindYaml(&testRoot{}, path, opt)
+	assert.NotNil(t, err)
+	var msgs []string
+	for _, e := range err.(BindErrors) {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		path + ": name: no data found for required field",
+		path + ":1:1: timeout: got [int], expected [time.Duration]",
+		path + ":5:5: listeners[1].tls.cert_path: no data found for required field",
+		path + ":6:7: listeners[1].tls.cret_path: unknown key",
+	}, msgs)
+	assert.Equal(t, 6, err.(BindErrors)[3].Origin.Line)
+
+	err = BindFile(&testRoot{}, path, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, path+":6:7: listeners[1].tls.cret_path: unknown key", err.(BindErrors)[3].Error())
+}
+
+func TestAggregateMapErrorsOrdered(t *testing.T) {
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+
+	data := map[string]interface{}{
+		"timeouts": map[string]interface{}{"d": 4, "b": 2, "e": 5, "a": 1, "c": 3},
+	}
+	for i := 0; i < 10; i++ {
+		err := Bind(&struct{ Timeouts map[string]time.Duration }{}, data, opt)
+		assert.NotNil(t, err)
+		var paths []string
+		for _, e := range err.(BindErrors) {
+			paths = append(paths, e.Path)
+		}
+		assert.Equal(t, []string{"timeouts.a", "timeouts.b", "timeouts.c", "timeouts.d", "timeouts.e"}, paths)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import "time"
+
+// testRoot is the configuration shared by the env, flags, loader, includes and errors tests.
+type testRoot struct {
+	Name      string `cf:"+required"`
+	Password  string `cf:"+secret,+env=DB_PASS" usage:"database password"`
+	Verbose   bool
+	Retries   *int
+	Timeout   time.Duration
+	Server    testServer
+	Listeners []*testListener
+	Tags      []string
+	Flexible  interface{}
+	Internal  string `cf:"+skip"`
+}
+
+type testServer struct {
+	Port    uint16 `usage:"port to listen on"`
+	Timeout *time.Duration
+	Tls     *testTls
+}
+
+type testListener struct {
+	Address string
+	Port    int
+	Enabled bool
+	Tls     *testTls
+}
+
+type testTls struct {
+	CertPath string `cf:"+required" usage:"path to the tls certificate"`
+}
@@ -0,0 +1,175 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"strconv"
+)
+
+// NewFlagSet returns a flag.FlagSet (with flag.ContinueOnError handling) containing the flags registered by AddFlags.
+func NewFlagSet(name string, cf interface{}, opt *Options) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := AddFlags(fs, cf, opt); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// AddFlags registers a flag in fs for every field of cf that is bound by a setter, named after the path of the field
+// (for example "-server.port", or "-listeners.0.address" for an element of a slice). Setting a flag overrides the
+// field in cf, converting the flag's text through the same setters as Bind. Flags are registered for the slice
+// elements that exist in cf at the time of the call; map and interface{} fields, and fields of a struct type nested
+// within itself, are not registered.
+//
+// The usage text of a flag is taken from the "usage" tag of its field. The current value of the field is shown as
+// the flag's default, except for fields tagged "+secret".
+func AddFlags(fs *flag.FlagSet, cf interface{}, opt *Options) error {
+	cfV := reflect.ValueOf(cf)
+	if cfV.Kind() != reflect.Ptr || cfV.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("provided type [%s] is not a pointer to a struct", cfV.Type())
+	}
+	root := func(bool) reflect.Value { return cfV.Elem() }
+	addStructFlags(fs, root, cfV.Elem().Type(), "", "", map[reflect.Type]bool{}, opt)
+	return nil
+}
+
+// fieldAccessor locates a field relative to the root of a configuration. When alloc is set, nil pointers along the way
+// are instantiated; otherwise a nil pointer produces an invalid value.
+type fieldAccessor func(alloc bool) reflect.Value
+
+// addStructFlags registers the flags for the fields of the struct type t. onPath holds the struct types being
+// registered above t, so that a type referring to itself (as in a linked list) is not followed forever.
+func addStructFlags(fs *flag.FlagSet, get fieldAccessor, t reflect.Type, prefix, path string, onPath map[reflect.Type]bool, opt *Options) {
+	if onPath[t] {
+		return
+	}
+	onPath[t] = true
+	defer delete(onPath, t)
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" { // exported
+			fd := parseFieldData(t.Field(i), opt)
+			if !fd.skip {
+				i := i
+				fieldGet := func(alloc bool) reflect.Value {
+					parent := deref(get(alloc), alloc, opt)
+					if !parent.IsValid() {
+						return reflect.Value{}
+					}
+					return parent.Field(i)
+				}
+				addFlags(fs, fieldGet, t.Field(i).Type, joinFlagName(prefix, fd.name), joinPath(path, fd.name), t.Field(i).Tag.Get("usage"), fd.secret, onPath, opt)
+			}
+		}
+	}
+}
+
+func addFlags(fs *flag.FlagSet, get fieldAccessor, t reflect.Type, name, path, usage string, secret bool, onPath map[reflect.Type]bool, opt *Options) {
+	_, hasSetter := opt.Setters[t]
+	_, hasElemSetter := opt.Setters[valueFromPtr(t)]
+	if hasSetter || hasElemSetter || isUnmarshaler(t) {
+		secret = secret || valueFromPtr(t) == secretType
+		fs.Var(&fieldFlag{get: get, t: t, path: path, secret: secret, opt: opt}, name, usage)
+
+	} else if valueFromPtr(t).Kind() == reflect.Struct {
+		addStructFlags(fs, get, valueFromPtr(t), name, path, onPath, opt)
+
+	} else if t.Kind() == reflect.Slice {
+		if current := get(false); current.IsValid() {
+			for i := 0; i < current.Len(); i++ {
+				i := i
+				elemGet := func(alloc bool) reflect.Value {
+					slice := get(alloc)
+					if !slice.IsValid() || i >= slice.Len() {
+						return reflect.Value{}
+					}
+					return slice.Index(i)
+				}
+				addFlags(fs, elemGet, t.Elem(), joinFlagName(name, strconv.Itoa(i)), indexPath(path, i), usage, secret, onPath, opt)
+			}
+		}
+	}
+}
+
+func deref(v reflect.Value, alloc bool, opt *Options) reflect.Value {
+	if v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !alloc {
+				return reflect.Value{}
+			}
+			v.Set(reflect.ValueOf(instantiateAsPtr(v.Type(), opt)))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+func joinFlagName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+type fieldFlag struct {
+	get    fieldAccessor
+	t      reflect.Type
+	path   string
+	secret bool
+	opt    *Options
+	raw    string
+}
+
+func (ff *fieldFlag) String() string {
+	if ff.get == nil || ff.secret {
+		return ""
+	}
+	v := ff.get(false)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+func (ff *fieldFlag) Set(s string) error {
+	target := ff.get(true)
+	b := valueBinder(ff.opt)
+	value, err := b.bindValue(s, target, ff.path)
+	if err != nil {
+		if errs, ok := err.(BindErrors); ok {
+			return errs[0].Err
+		}
+		return err
+	}
+	target.Set(value)
+	ff.raw = s
+	return nil
+}
+
+// IsBoolFlag allows boolean fields to be set with a bare "-name".
+func (ff *fieldFlag) IsBoolFlag() bool {
+	return ff.t != nil && valueFromPtr(ff.t).Kind() == reflect.Bool
+}
@@ -0,0 +1,103 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlags(t *testing.T) {
+	root := &testRoot{}
+	data := map[string]interface{}{
+		"name":     "from file",
+		"password": "s3cr3t",
+		"server": map[string]interface{}{
+			"port":    1280,
+			"timeout": "30s",
+		},
+		"listeners": []interface{}{
+			map[string]interface{}{"address": "0.0.0.0:1280"},
+		},
+	}
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+
+	fs, err := NewFlagSet("test", root, DefaultOptions())
+	assert.Nil(t, err)
+
+	err = fs.Parse([]string{"-name", "from flag", "-verbose", "-retries", "3", "--server.port=8080", "-server.tls.cert_path", "/etc/cert.pem", "-listeners.0.address", "127.0.0.1:1280"})
+	assert.Nil(t, err)
+	assert.Equal(t, "from flag", root.Name)
+	assert.True(t, root.Verbose)
+	assert.Equal(t, 3, *root.Retries)
+	assert.Equal(t, uint16(8080), root.Server.Port)
+	assert.Equal(t, 30*time.Second, *root.Server.Timeout)
+	assert.Equal(t, "/etc/cert.pem", root.Server.Tls.CertPath)
+	assert.Equal(t, "127.0.0.1:1280", root.Listeners[0].Address)
+	assert.Nil(t, fs.Lookup("internal"))
+}
+
+func TestFlagsInvalid(t *testing.T) {
+	root := &testRoot{}
+	fs, err := NewFlagSet("test", root, DefaultOptions())
+	assert.Nil(t, err)
+	fs.SetOutput(&bytes.Buffer{})
+
+	err = fs.Parse([]string{"-server.port", "70000"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "value [70000] overflows [uint16]")
+
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+	fs, err = NewFlagSet("test", root, opt)
+	assert.Nil(t, err)
+	fs.SetOutput(&bytes.Buffer{})
+
+	err = fs.Parse([]string{"-server.port", "oops"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid value [oops] for [uint16]")
+}
+
+func TestFlagsUsage(t *testing.T) {
+	root := &testRoot{Password: "s3cr3t", Server: testServer{Port: 1280}}
+	fs, err := NewFlagSet("test", root, DefaultOptions())
+	assert.Nil(t, err)
+
+	out := &bytes.Buffer{}
+	fs.SetOutput(out)
+	fs.PrintDefaults()
+	usage := out.String()
+	assert.Contains(t, usage, "port to listen on (default 1280)")
+	assert.Contains(t, usage, "path to the tls certificate")
+	assert.Contains(t, usage, "database password")
+	assert.False(t, strings.Contains(usage, "s3cr3t"))
+}
+
+func TestFlagsSelfReferential(t *testing.T) {
+	root := &dumpCfNode{Name: "head", Parent: &dumpCfNode{Name: "tail"}}
+	fs, err := NewFlagSet("test", root, DefaultOptions())
+	assert.Nil(t, err)
+	assert.NotNil(t, fs.Lookup("name"))
+	assert.Nil(t, fs.Lookup("parent.name")) // the type is not followed into itself
+
+	assert.Nil(t, fs.Parse([]string{"-name", "new head"}))
+	assert.Equal(t, "new head", root.Name)
+}
@@ -0,0 +1,174 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IncludeKey is the reserved yaml key for including other files. Its value is a file name or glob (or a list of them),
+// resolved relative to the including file. The included data is merged into the map containing the key; keys declared
+// alongside the include take precedence over included ones, and later includes take precedence over earlier ones. A
+// map containing only an include may include a value of any shape, such as a list.
+const IncludeKey = "$include"
+
+type includer struct {
+	dir     string
+	origins Origins
+	stack   []string
+}
+
+func (inc *includer) resolve(v interface{}, path string) (interface{}, error) {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		for k, kv := range vt {
+			if k != IncludeKey {
+				resolved, err := inc.resolve(kv, joinPath(path, k))
+				if err != nil {
+					return nil, err
+				}
+				vt[k] = resolved
+			}
+		}
+		if patterns, found := vt[IncludeKey]; found {
+			delete(vt, IncludeKey)
+			return inc.include(patterns, vt, path)
+		}
+		return vt, nil
+
+	case []interface{}:
+		for i, iv := range vt {
+			resolved, err := inc.resolve(iv, indexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			vt[i] = resolved
+		}
+		return vt, nil
+
+	default:
+		return v, nil
+	}
+}
+
+func (inc *includer) include(patternsV interface{}, local map[string]interface{}, path string) (interface{}, error) {
+	at := inc.origins[joinPath(path, IncludeKey)]
+	var patterns []string
+	switch vt := patternsV.(type) {
+	case string:
+		patterns = append(patterns, vt)
+	case []interface{}:
+		for _, pattern := range vt {
+			if ps, ok := pattern.(string); ok {
+				patterns = append(patterns, ps)
+			} else {
+				return nil, errors.Errorf("%s: invalid include '%v'", at, pattern)
+			}
+		}
+	default:
+		return nil, errors.Errorf("%s: invalid include '%v'", at, patternsV)
+	}
+
+	var included []interface{}
+	includedOrigins := make(Origins)
+	for _, pattern := range patterns {
+		files, err := inc.glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: include '%s'", at, pattern)
+		}
+		for _, file := range files {
+			absFile, err := filepath.Abs(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: include '%s'", at, file)
+			}
+			for i, including := range inc.stack {
+				if including == absFile {
+					chain := append(append([]string{}, inc.stack[i:]...), absFile)
+					return nil, errors.Errorf("%s: include cycle %s", at, strings.Join(chain, " -> "))
+				}
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: include '%s'", at, file)
+			}
+			v, origins, err := decodeYamlFragment(file, data, inc.stack)
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, v)
+			for fragmentPath, origin := range origins {
+				includedOrigins[rebasePath(path, fragmentPath)] = origin
+			}
+		}
+	}
+	for originPath, origin := range includedOrigins {
+		if _, found := inc.origins[originPath]; !found {
+			inc.origins[originPath] = origin
+		}
+	}
+
+	if len(included) == 1 {
+		if _, ok := included[0].(map[string]interface{}); !ok && len(local) == 0 {
+			return included[0], nil
+		}
+	}
+	merged := make(map[string]interface{})
+	for _, v := range included {
+		if v == nil {
+			continue
+		}
+		fragment, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("%s: cannot merge included [%T] into a map", at, v)
+		}
+		merged = mergeData(merged, fragment)
+	}
+	return mergeData(merged, local), nil
+}
+
+// glob returns the files matching pattern (relative to the including file), sorted by name. A pattern without glob
+// meta-characters must match an existing file.
+func (inc *includer) glob(pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(inc.dir, pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// rebasePath places a path relative to an included fragment at path within the including document.
+func rebasePath(path, fragmentPath string) string {
+	if strings.HasPrefix(fragmentPath, "[") {
+		return path + fragmentPath
+	}
+	return joinPath(path, fragmentPath)
+}
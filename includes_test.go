@@ -0,0 +1,87 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInclude(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"main.yml": `name: main
+listeners:
+  $include: conf.d/listeners.yml
+server:
+  $include: [conf.d/server-*.yml]
+  port: 1280
+`,
+		"conf.d/listeners.yml": `- address: 0.0.0.0
+  port: 1280
+- $include: listener.yml
+`,
+		"conf.d/listener.yml": "address: 127.0.0.1\nport: 1281\n",
+		"conf.d/server-a.yml": "port: 1\ntimeout: 5s\n",
+		"conf.d/server-b.yml": "timeout: 10s\n",
+	})
+
+	root := &testRoot{}
+	err := BindYaml(root, filepath.Join(dir, "main.yml"), DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "main", root.Name)
+	assert.Equal(t, 2, len(root.Listeners))
+	assert.Equal(t, 1280, root.Listeners[0].Port)
+	assert.Equal(t, "127.0.0.1", root.Listeners[1].Address)
+	assert.Equal(t, uint16(1280), root.Server.Port)
+	assert.Equal(t, 10*time.Second, *root.Server.Timeout)
+
+	l := NewLoader(YamlSource(filepath.Join(dir, "main.yml")))
+	err = l.Load(&testRoot{}, DefaultOptions())
+	assert.Nil(t, err)
+	origin, _ := l.Origin("listeners[1].port")
+	assert.Equal(t, filepath.Join(dir, "conf.d/listener.yml")+":2:1", origin.String())
+	origin, _ = l.Origin("server.timeout")
+	assert.Equal(t, filepath.Join(dir, "conf.d/server-b.yml")+":1:1", origin.String())
+	origin, _ = l.Origin("server.port")
+	assert.Equal(t, filepath.Join(dir, "main.yml")+":6:3", origin.String())
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.yml": "name: a\nserver:\n  $include: b.yml\n",
+		"b.yml": "$include: a.yml\n",
+	})
+
+	err := BindYaml(&testRoot{}, filepath.Join(dir, "a.yml"), DefaultOptions())
+	assert.NotNil(t, err)
+	a := filepath.Join(dir, "a.yml")
+	b := filepath.Join(dir, "b.yml")
+	assert.Equal(t, b+":1:1: include cycle "+a+" -> "+b+" -> "+a, err.Error())
+}
+
+func TestIncludeMissing(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"main.yml": "name: main\nlisteners:\n  $include: missing.yml\n",
+	})
+
+	err := BindYaml(&testRoot{}, filepath.Join(dir, "main.yml"), DefaultOptions())
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), filepath.Join(dir, "main.yml")+":3:3: include 'missing.yml'"))
+}
@@ -0,0 +1,316 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Origin describes where a configuration value came from. Kind is one of "file", "env", "flag" or "data"; Name is the
+// file path, environment variable, flag or data source name. Line and Column are set for yaml files.
+type Origin struct {
+	Kind   string
+	Name   string
+	Line   int
+	Column int
+}
+
+func (o Origin) String() string {
+	switch o.Kind {
+	case "file":
+		if o.Line > 0 {
+			return fmt.Sprintf("%s:%d:%d", o.Name, o.Line, o.Column)
+		}
+		return o.Name
+	case "env":
+		return "env " + o.Name
+	case "flag":
+		return "flag -" + o.Name
+	default:
+		return o.Name
+	}
+}
+
+// Origins maps configuration paths (like "listeners[0].address") to the origins of their values.
+type Origins map[string]Origin
+
+// Source supplies one layer of configuration to a Loader. A source returns data overlaid with its values, along with
+// the origins of the values it supplied.
+type Source func(cf interface{}, data map[string]interface{}, opt *Options) (map[string]interface{}, Origins, error)
+
+// Loader binds configuration merged from an ordered list of sources, where later sources take precedence. Maps are
+// merged key by key; any other value (including a slice) supplied by a later source replaces the earlier value.
+type Loader struct {
+	sources []Source
+	origins Origins
+}
+
+func NewLoader(sources ...Source) *Loader {
+	return &Loader{sources: sources}
+}
+
+func (l *Loader) AddSource(s Source) *Loader {
+	l.sources = append(l.sources, s)
+	return l
+}
+
+// Load merges the data from every source and binds it into cf.
+func (l *Loader) Load(cf interface{}, opt *Options) error {
+	data := make(map[string]interface{})
+	origins := make(Origins)
+	for _, source := range l.sources {
+		var sourceOrigins Origins
+		var err error
+		data, sourceOrigins, err = source(cf, data, opt)
+		if err != nil {
+			return err
+		}
+		for path, origin := range sourceOrigins {
+			origins[path] = origin
+		}
+	}
+	// discard the origins of values replaced by later sources
+	for path := range origins {
+		if _, found := lookupPath(data, path); !found {
+			delete(origins, path)
+		}
+	}
+	l.origins = origins
+	return locateErrors(Bind(cf, data, opt), origins, Origin{})
+}
+
+// Origin returns the origin of the value at path, as of the last call to Load.
+func (l *Loader) Origin(path string) (Origin, bool) {
+	origin, found := l.origins[path]
+	return origin, found
+}
+
+// Origins returns the origins of all values, as of the last call to Load.
+func (l *Loader) Origins() Origins {
+	return l.origins
+}
+
+// YamlSource merges a yaml file, recording the line and column of each value.
+func YamlSource(path string) Source {
+	return func(_ interface{}, data map[string]interface{}, _ *Options) (map[string]interface{}, Origins, error) {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error reading [%s]", path)
+		}
+		fileData, origins, err := decodeYaml(path, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mergeData(data, fileData), origins, nil
+	}
+}
+
+// FileSource merges a file decoded by the decoder registered in the options for the file's extension. Values are
+// attributed to their line and column when the decoder is positional, otherwise to the file.
+func FileSource(path string) Source {
+	return func(_ interface{}, data map[string]interface{}, opt *Options) (map[string]interface{}, Origins, error) {
+		decoder, err := opt.decoder(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error reading [%s]", path)
+		}
+		fileData, origins, err := decoder(path, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if origins == nil {
+			origins = dataOrigins(fileData, Origin{Kind: "file", Name: path})
+		}
+		return mergeData(data, fileData), origins, nil
+	}
+}
+
+// DataSource merges a data map, attributing its values to name.
+func DataSource(name string, sourceData map[string]interface{}) Source {
+	return func(_ interface{}, data map[string]interface{}, _ *Options) (map[string]interface{}, Origins, error) {
+		return mergeData(data, sourceData), dataOrigins(sourceData, Origin{Kind: "data", Name: name}), nil
+	}
+}
+
+// EnvSource overlays environment variables, as described by OverlayEnv.
+func EnvSource(prefix string) Source {
+	return func(cf interface{}, data map[string]interface{}, opt *Options) (map[string]interface{}, Origins, error) {
+		t := valueFromPtr(reflect.TypeOf(cf))
+		if t.Kind() != reflect.Struct {
+			return nil, nil, errors.Errorf("provided type [%s] is not a struct", t)
+		}
+		o := &envOverlay{env: environment(), opt: opt, origins: make(Origins)}
+		out, err := o.overlayStruct(t, data, prefix, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		return out, o.origins, nil
+	}
+}
+
+// FlagSource overlays the flags set in fs, which must have been registered by AddFlags (or NewFlagSet) and parsed
+// before the Loader is loaded.
+func FlagSource(fs *flag.FlagSet) Source {
+	return func(_ interface{}, data map[string]interface{}, _ *Options) (map[string]interface{}, Origins, error) {
+		origins := make(Origins)
+		var out interface{} = data
+		fs.Visit(func(f *flag.Flag) {
+			if ff, ok := f.Value.(*fieldFlag); ok {
+				out = setPath(out, pathSegments(ff.path), ff.raw)
+				origins[ff.path] = Origin{Kind: "flag", Name: f.Name}
+			}
+		})
+		return out.(map[string]interface{}), origins, nil
+	}
+}
+
+// mergeData returns a copy of dst with src merged into it. Maps are merged recursively; other values in src replace
+// those in dst.
+func mergeData(dst, src map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		out[k] = v
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := out[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			out[k] = mergeData(dstMap, srcMap)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// dataOrigins attributes every value in data to origin.
+func dataOrigins(data map[string]interface{}, origin Origin) Origins {
+	origins := make(Origins)
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		if path != "" {
+			origins[path] = origin
+		}
+		switch vt := v.(type) {
+		case map[string]interface{}:
+			for k, kv := range vt {
+				walk(kv, joinPath(path, k))
+			}
+		case []interface{}:
+			for i, iv := range vt {
+				walk(iv, indexPath(path, i))
+			}
+		}
+	}
+	walk(data, "")
+	return origins
+}
+
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// pathSegments splits a path like "listeners[0].address" into its keys and indexes.
+func pathSegments(path string) []pathSegment {
+	var segs []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []int
+		for strings.HasSuffix(key, "]") {
+			open := strings.LastIndex(key, "[")
+			if open == -1 {
+				break
+			}
+			index, err := strconv.Atoi(key[open+1 : len(key)-1])
+			if err != nil {
+				break
+			}
+			indexes = append([]int{index}, indexes...)
+			key = key[:open]
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		for _, index := range indexes {
+			segs = append(segs, pathSegment{index: index, isIndex: true})
+		}
+	}
+	return segs
+}
+
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	v := data
+	for _, seg := range pathSegments(path) {
+		if seg.isIndex {
+			vV := reflect.ValueOf(v)
+			if v == nil || vV.Kind() != reflect.Slice || seg.index >= vV.Len() {
+				return nil, false
+			}
+			v = vV.Index(seg.index).Interface()
+		} else {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[seg.key]; !ok {
+				return nil, false
+			}
+		}
+	}
+	return v, true
+}
+
+// setPath returns a copy of data with value set at the path described by segs, creating maps and growing slices as
+// needed.
+func setPath(data interface{}, segs []pathSegment, value interface{}) interface{} {
+	if len(segs) == 0 {
+		return value
+	}
+	seg := segs[0]
+	if seg.isIndex {
+		var out []interface{}
+		if dataV := reflect.ValueOf(data); data != nil && dataV.Kind() == reflect.Slice {
+			for i := 0; i < dataV.Len(); i++ {
+				out = append(out, dataV.Index(i).Interface())
+			}
+		}
+		for len(out) <= seg.index {
+			out = append(out, nil)
+		}
+		out[seg.index] = setPath(out[seg.index], segs[1:], value)
+		return out
+	}
+	out := make(map[string]interface{})
+	if m, ok := data.(map[string]interface{}); ok {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	out[seg.key] = setPath(out[seg.key], segs[1:], value)
+	return out
+}
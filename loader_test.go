@@ -0,0 +1,108 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoader(t *testing.T) {
+	base := writeTestFile(t, "base.yml", `name: base
+server:
+  port: 1280
+  timeout: 30s
+listeners:
+  - address: 0.0.0.0:1280
+  - address: 0.0.0.0:1281
+`)
+	site := writeTestFile(t, "site.yml", `server:
+  port: 1281
+listeners:
+  - address: 10.0.0.1:1280
+`)
+	t.Setenv("APP_LISTENERS_0_ENABLED", "true")
+
+	root := &testRoot{}
+	opt := DefaultOptions()
+	fs, err := NewFlagSet("test", root, opt)
+	assert.Nil(t, err)
+	err = fs.Parse([]string{"-name", "flag"})
+	assert.Nil(t, err)
+
+	l := NewLoader(YamlSource(base), YamlSource(site)).AddSource(EnvSource("APP")).AddSource(FlagSource(fs))
+	err = l.Load(root, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "flag", root.Name)
+	assert.Equal(t, uint16(1281), root.Server.Port)
+	assert.Equal(t, 30*time.Second, *root.Server.Timeout)
+	assert.Equal(t, 1, len(root.Listeners))
+	assert.Equal(t, "10.0.0.1:1280", root.Listeners[0].Address)
+	assert.True(t, root.Listeners[0].Enabled)
+
+	origin, found := l.Origin("server.timeout")
+	assert.True(t, found)
+	assert.Equal(t, base+":4:3", origin.String())
+	origin, _ = l.Origin("server.port")
+	assert.Equal(t, site+":2:3", origin.String())
+	origin, _ = l.Origin("listeners[0].address")
+	assert.Equal(t, site+":4:5", origin.String())
+	origin, _ = l.Origin("listeners[0].enabled")
+	assert.Equal(t, "env APP_LISTENERS_0_ENABLED", origin.String())
+	origin, _ = l.Origin("name")
+	assert.Equal(t, "flag -name", origin.String())
+	_, found = l.Origin("listeners[1].address")
+	assert.False(t, found)
+
+	out := DumpOrigins(root, l.Origins(), opt)
+	assert.True(t, strings.Contains(out, `"flag" # flag -name`))
+	assert.True(t, strings.Contains(out, "30s # "+base+":4:3"))
+}
+
+func TestDataSource(t *testing.T) {
+	root := &testRoot{}
+	l := NewLoader(
+		DataSource("defaults", map[string]interface{}{"name": "default", "server": map[string]interface{}{"port": 1280}}),
+		FileSource(writeTestFile(t, "cf.json", `{"server": {"timeout": "5s"}}`)),
+	)
+	err := l.Load(root, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "default", root.Name)
+	assert.Equal(t, uint16(1280), root.Server.Port)
+	assert.Equal(t, 5*time.Second, *root.Server.Timeout)
+	origin, _ := l.Origin("server.port")
+	assert.Equal(t, "defaults", origin.String())
+}
+
+func TestPaths(t *testing.T) {
+	data := map[string]interface{}{
+		"listeners": []interface{}{
+			map[string]interface{}{"address": "a"},
+		},
+	}
+	v, found := lookupPath(data, "listeners[0].address")
+	assert.True(t, found)
+	assert.Equal(t, "a", v)
+
+	out := setPath(data, pathSegments("listeners[1].address"), "b")
+	v, _ = lookupPath(out, "listeners[1].address")
+	assert.Equal(t, "b", v)
+	_, found = lookupPath(data, "listeners[1].address")
+	assert.False(t, found)
+}
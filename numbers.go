@@ -0,0 +1,179 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// toInt64 converts numeric data (any integer or float kind, or a numeric string) into a value for the signed integer
+// type t. Fractional values and values outside the range of t are rejected.
+func toInt64(v interface{}, t reflect.Type) (int64, error) {
+	var i int64
+	vV := reflect.ValueOf(v)
+	switch vV.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = vV.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if vV.Uint() > math.MaxInt64 {
+			return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+		}
+		i = int64(vV.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		f, err := integralFloat(vV.Float(), t)
+		if err != nil {
+			return 0, err
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+		}
+		i = int64(f)
+
+	case reflect.String:
+		s := strings.TrimSpace(vV.String())
+		parsed, err := strconv.ParseInt(integerText(s))
+		if err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+				return 0, errors.Errorf("value [%s] overflows [%s]", s, t)
+			}
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil {
+				return 0, errors.Errorf("invalid value [%s] for [%s]", s, t)
+			}
+			return toInt64(f, t)
+		}
+		i = parsed
+
+	default:
+		return 0, errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), t)
+	}
+	if bits := t.Bits(); bits < 64 {
+		if i < -1<<(bits-1) || i > 1<<(bits-1)-1 {
+			return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+		}
+	}
+	return i, nil
+}
+
+// toUint64 converts numeric data (any integer or float kind, or a numeric string) into a value for the unsigned integer
+// type t. Negative, fractional and out of range values are rejected.
+func toUint64(v interface{}, t reflect.Type) (uint64, error) {
+	var u uint64
+	vV := reflect.ValueOf(v)
+	switch vV.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if vV.Int() < 0 {
+			return 0, errors.Errorf("negative value [%v] for [%s]", v, t)
+		}
+		u = uint64(vV.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = vV.Uint()
+
+	case reflect.Float32, reflect.Float64:
+		f, err := integralFloat(vV.Float(), t)
+		if err != nil {
+			return 0, err
+		}
+		if f < 0 {
+			return 0, errors.Errorf("negative value [%v] for [%s]", v, t)
+		}
+		if f >= math.MaxUint64 {
+			return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+		}
+		u = uint64(f)
+
+	case reflect.String:
+		s := strings.TrimSpace(vV.String())
+		parsed, err := strconv.ParseUint(integerText(s))
+		if err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+				return 0, errors.Errorf("value [%s] overflows [%s]", s, t)
+			}
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil {
+				return 0, errors.Errorf("invalid value [%s] for [%s]", s, t)
+			}
+			return toUint64(f, t)
+		}
+		u = parsed
+
+	default:
+		return 0, errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), t)
+	}
+	if bits := t.Bits(); bits < 64 && u > 1<<bits-1 {
+		return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+	}
+	return u, nil
+}
+
+// toFloat64 converts numeric data (any integer or float kind, or a numeric string) into a value for the float type t.
+func toFloat64(v interface{}, t reflect.Type) (float64, error) {
+	var f float64
+	vV := reflect.ValueOf(v)
+	switch vV.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(vV.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(vV.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		f = vV.Float()
+
+	case reflect.String:
+		s := strings.TrimSpace(vV.String())
+		parsed, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errors.Errorf("invalid value [%s] for [%s]", s, t)
+		}
+		f = parsed
+
+	default:
+		return 0, errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), t)
+	}
+	if t.Bits() == 32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return 0, errors.Errorf("value [%v] overflows [%s]", v, t)
+	}
+	return f, nil
+}
+
+func integralFloat(f float64, t reflect.Type) (float64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, errors.Errorf("value [%v] is not an integer, expected [%s]", f, t)
+	}
+	return f, nil
+}
+
+// integerText returns the integer string s prepared for strconv: hexadecimal with a "0x" prefix, otherwise decimal, so
+// that zero-padded values like "0755" are not read as octal.
+func integerText(s string) (string, int, int) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return sign + s[2:], 16, 64
+	}
+	return sign + s, 10, 64
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNumericCoercion(t *testing.T) {
+	root := &struct {
+		Port    uint16
+		Count   int
+		Small   int8
+		Size    uint
+		Ptr     uintptr
+		Ratio   float32
+		Big     uint64
+		Retries *int
+	}{}
+
+	var data = map[string]interface{}{
+		"port":    float64(8080),
+		"count":   "42",
+		"small":   int64(-128),
+		"size":    "0x10",
+		"ptr":     7,
+		"ratio":   "0.25",
+		"big":     uint64(18446744073709551615),
+		"retries": " 3 ",
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(8080), root.Port)
+	assert.Equal(t, 42, root.Count)
+	assert.Equal(t, int8(-128), root.Small)
+	assert.Equal(t, uint(16), root.Size)
+	assert.Equal(t, uintptr(7), root.Ptr)
+	assert.Equal(t, float32(0.25), root.Ratio)
+	assert.Equal(t, uint64(18446744073709551615), root.Big)
+	assert.Equal(t, 3, *root.Retries)
+}
+
+func TestNumericLeadingZeros(t *testing.T) {
+	root := &struct {
+		Mode  int
+		Port  uint16
+		Flags uint32
+	}{}
+
+	err := Bind(root, map[string]interface{}{"mode": "0755", "port": "0800", "flags": "0x0755"}, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, 755, root.Mode)
+	assert.Equal(t, uint16(800), root.Port)
+	assert.Equal(t, uint32(0x755), root.Flags)
+}
+
+func TestNumericCoercionErrors(t *testing.T) {
+	root := &struct {
+		Port  uint16
+		Count int
+		Small int8
+		Size  uint
+		Ratio float32
+		Flag  int
+	}{}
+
+	var data = map[string]interface{}{
+		"port":  70000,
+		"count": 1.5,
+		"small": "128",
+		"size":  -1,
+		"ratio": 1e39,
+		"flag":  true,
+	}
+
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+	err := Bind(root, data, opt)
+	assert.NotNil(t, err)
+	var msgs []string
+	for _, e := range err.(BindErrors) {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		"port: value [70000] overflows [uint16]",
+		"count: value [1.5] is not an integer, expected [int]",
+		"small: value [128] overflows [int8]",
+		"size: negative value [-1] for [uint]",
+		"ratio: value [1e+39] overflows [float32]",
+		"flag: got [bool], expected [int]",
+	}, msgs)
+}
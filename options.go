@@ -17,24 +17,57 @@
 package cf
 
 import (
+	"github.com/pkg/errors"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 )
 
 type Instantiator func() interface{}
 type Setter func(v interface{}, f reflect.Value, opt *Options) error
+type Getter func(f reflect.Value, opt *Options) (interface{}, error)
 type FlexibleSetter func(v interface{}, opt *Options) (interface{}, error)
 type Wiring func(cf interface{}) error
 type NameConverter func(f reflect.StructField) string
 type VariableResolver func(name string) (interface{}, bool)
+type UnknownKeyHandler func(path string)
+type Decoder func(path string, data []byte) (map[string]interface{}, error)
+type PositionalDecoder func(path string, data []byte) (map[string]interface{}, Origins, error)
 
 type Options struct {
 	Instantiators         map[reflect.Type]Instantiator
 	Setters               map[reflect.Type]Setter
+	Getters               map[reflect.Type]Getter
 	FlexibleSetters       map[string]FlexibleSetter
 	Wirings               map[reflect.Type][]Wiring
 	NameConverter         NameConverter
 	VariableResolverChain []VariableResolver
+	VariableNamespaces    map[string]VariableResolver
+	Decoders              map[string]Decoder
+	PositionalDecoders    map[string]PositionalDecoder
+
+	// AggregateErrors keeps binding after a failure, returning every failure in a single BindErrors value.
+	AggregateErrors bool
+
+	// Strict fails binding for any data key that is not claimed by a field of the bound structure. '+skip' fields do not
+	// claim their keys.
+	Strict bool
+
+	// UnknownKeyHandler, when set, is called with the path of every data key that is not claimed by a field of the
+	// bound structure. Unlike Strict, it does not fail the binding.
+	UnknownKeyHandler UnknownKeyHandler
+
+	// SecretPlaceholder replaces the values of '+secret' fields in dumps. Defaults to "<SECRET>" when empty.
+	SecretPlaceholder string
+
+	// DumpOptions controls the layout of Dump and DumpTo.
+	DumpOptions DumpOptions
+
+	path     string
+	flexible bool
+	resolved bool // data has had its variables expanded (as handed to flexible setters)
+	self     map[string]interface{}
 }
 
 func DefaultOptions() *Options {
@@ -42,18 +75,35 @@ func DefaultOptions() *Options {
 	opt := &Options{
 		Setters: map[reflect.Type]Setter{
 			reflect.TypeOf(0):          intSetter,
-			reflect.TypeOf(int8(0)):    int8Setter,
-			reflect.TypeOf(uint8(0)):   uint8Setter,
-			reflect.TypeOf(int16(0)):   int16Setter,
-			reflect.TypeOf(uint16(0)):  uint16Setter,
-			reflect.TypeOf(int32(0)):   int32Setter,
-			reflect.TypeOf(uint32(0)):  uint32Setter,
-			reflect.TypeOf(int64(0)):   int64Setter,
-			reflect.TypeOf(uint64(0)):  uint64Setter,
-			reflect.TypeOf(float64(0)): float64Setter,
+			reflect.TypeOf(int8(0)):    intSetter,
+			reflect.TypeOf(int16(0)):   intSetter,
+			reflect.TypeOf(int32(0)):   intSetter,
+			reflect.TypeOf(int64(0)):   intSetter,
+			reflect.TypeOf(uint(0)):    uintSetter,
+			reflect.TypeOf(uint8(0)):   uintSetter,
+			reflect.TypeOf(uint16(0)):  uintSetter,
+			reflect.TypeOf(uint32(0)):  uintSetter,
+			reflect.TypeOf(uint64(0)):  uintSetter,
+			reflect.TypeOf(uintptr(0)): uintSetter,
+			reflect.TypeOf(float32(0)): floatSetter,
+			reflect.TypeOf(float64(0)): floatSetter,
 			reflect.TypeOf(true):       boolSetter,
 			reflect.TypeOf(""):         stringSetter,
 			reflect.TypeOf(td):         timeDurationSetter,
+			secretType:                 secretSetter,
+		},
+		Getters: map[reflect.Type]Getter{
+			reflect.TypeOf(td): timeDurationGetter,
+			secretType:         secretGetter,
+		},
+		Decoders: map[string]Decoder{
+			".yaml": YamlDecoder,
+			".yml":  YamlDecoder,
+			".json": JsonDecoder,
+		},
+		PositionalDecoders: map[string]PositionalDecoder{
+			".yaml": YamlPositionalDecoder,
+			".yml":  YamlPositionalDecoder,
 		},
 		NameConverter: SnakeCaseNameConverter,
 	}
@@ -76,6 +126,15 @@ func (opt *Options) AddSetter(t reflect.Type, s Setter) *Options {
 	return opt
 }
 
+// AddGetter registers the reverse of a setter, used by Unbind to produce data for values of type t.
+func (opt *Options) AddGetter(t reflect.Type, g Getter) *Options {
+	if opt.Getters == nil {
+		opt.Getters = make(map[reflect.Type]Getter)
+	}
+	opt.Getters[t] = g
+	return opt
+}
+
 func (opt *Options) AddFlexibleSetter(typeName string, fs FlexibleSetter) *Options {
 	if opt.FlexibleSetters == nil {
 		opt.FlexibleSetters = make(map[string]FlexibleSetter)
@@ -92,17 +151,97 @@ func (opt *Options) AddWiring(t reflect.Type, w Wiring) *Options {
 	return opt
 }
 
+// AddDecoder registers the decoder used by BindFile for files with the extension ext (for example ".toml"), replacing
+// any positional decoder registered for ext.
+func (opt *Options) AddDecoder(ext string, d Decoder) *Options {
+	if opt.Decoders == nil {
+		opt.Decoders = make(map[string]Decoder)
+	}
+	opt.Decoders[strings.ToLower(ext)] = d
+	delete(opt.PositionalDecoders, strings.ToLower(ext))
+	return opt
+}
+
+// AddPositionalDecoder registers a decoder that also returns the origin (file, line and column) of every value, so
+// that binding errors are located within files with the extension ext. It takes precedence over a Decoder registered
+// for ext.
+func (opt *Options) AddPositionalDecoder(ext string, d PositionalDecoder) *Options {
+	if opt.PositionalDecoders == nil {
+		opt.PositionalDecoders = make(map[string]PositionalDecoder)
+	}
+	opt.PositionalDecoders[strings.ToLower(ext)] = d
+	return opt
+}
+
+// decoder returns the decoder registered for the extension of path, as a PositionalDecoder.
+func (opt *Options) decoder(path string) (PositionalDecoder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if d, found := opt.PositionalDecoders[ext]; found {
+		return d, nil
+	}
+	if d, found := opt.Decoders[ext]; found {
+		return withoutOrigins(d), nil
+	}
+	return nil, errors.Errorf("no decoder for extension '%s' [%s]", ext, path)
+}
+
+func withoutOrigins(d Decoder) PositionalDecoder {
+	return func(path string, data []byte) (map[string]interface{}, Origins, error) {
+		dataMap, err := d(path, data)
+		return dataMap, nil, err
+	}
+}
+
 func (opt *Options) SetNameConverter(nc NameConverter) *Options {
 	opt.NameConverter = nc
 	return opt
 }
 
+func (opt *Options) SetDumpOptions(do DumpOptions) *Options {
+	opt.DumpOptions = do
+	return opt
+}
+
+func (opt *Options) SetUnknownKeyHandler(ukh UnknownKeyHandler) *Options {
+	opt.UnknownKeyHandler = ukh
+	return opt
+}
+
 func (opt *Options) AddVariableResolver(vr VariableResolver) *Options {
 	opt.VariableResolverChain = append(opt.VariableResolverChain, vr)
 	return opt
 }
 
+// AddVariableNamespace registers a resolver for variables prefixed with "<namespace>:", as in "${namespace:name}". The
+// resolver receives the name without the prefix. The "self" namespace is reserved for paths within the data being
+// bound, as in "${self:server.port}".
+func (opt *Options) AddVariableNamespace(namespace string, vr VariableResolver) *Options {
+	if opt.VariableNamespaces == nil {
+		opt.VariableNamespaces = make(map[string]VariableResolver)
+	}
+	opt.VariableNamespaces[namespace] = vr
+	return opt
+}
+
+// AddBuiltinVariableNamespaces registers the built-in "env" (EnvVariableResolver) and "file" (FileVariableResolver)
+// namespaces. They are not registered by DefaultOptions, since they let any configuration value read environment
+// variables and files readable by the process; applications opt in when their configuration is trusted.
+func (opt *Options) AddBuiltinVariableNamespaces() *Options {
+	opt.AddVariableNamespace("env", EnvVariableResolver)
+	opt.AddVariableNamespace("file", FileVariableResolver)
+	return opt
+}
+
 func (opt *Options) resolveVariable(vname string) (interface{}, bool) {
+	if idx := strings.Index(vname, ":"); idx != -1 {
+		namespace, name := vname[:idx], vname[idx+1:]
+		if namespace == "self" {
+			return lookupPath(opt.self, name)
+		}
+		if vr, found := opt.VariableNamespaces[namespace]; found {
+			return vr(name)
+		}
+	}
 	for _, vr := range opt.VariableResolverChain {
 		if vvalue, found := vr(vname); found {
 			return vvalue, true
@@ -110,3 +249,19 @@ func (opt *Options) resolveVariable(vname string) (interface{}, bool) {
 	}
 	return nil, false
 }
+
+func (opt *Options) secretPlaceholder() string {
+	if opt.SecretPlaceholder == "" {
+		return redacted
+	}
+	return opt.SecretPlaceholder
+}
+
+// at returns a copy of the options positioned at path, so that nested calls to Bind (from setters and flexible setters)
+// report errors relative to the root of the configuration.
+func (opt *Options) at(path string) *Options {
+	nopt := *opt
+	nopt.path = path
+	nopt.flexible = false
+	return &nopt
+}
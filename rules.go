@@ -0,0 +1,178 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a validation rule declared in the cf tag of a field: "+min=<n>", "+max=<n>", "+oneof=<a>|<b>|...",
+// "+pattern=<regexp>" or "+nonempty". Rules are checked by Bind once a field is bound. Fields absent from the data
+// (and without a default) are optional, and are only checked against "+nonempty".
+//
+// For numeric fields, "+min" and "+max" bound the value itself (time.Duration fields accept durations like "1s"); for
+// strings, slices and maps they bound the length.
+type Rule struct {
+	Name string
+	Arg  string
+}
+
+// Validator is implemented by configuration structures that validate themselves. Bind calls Validate once the fields of
+// the structure are bound, before executing any wirings for the type.
+type Validator interface {
+	Validate() error
+}
+
+var ruleNames = []string{"min", "max", "oneof", "pattern", "nonempty"}
+
+// Rules returns the validation rules declared in the cf tag of f.
+func Rules(f reflect.StructField) []Rule {
+	var rules []Rule
+	for _, token := range tagTokens(f.Tag.Get("cf")) {
+		if r, ok := parseRule(token); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func parseRule(token string) (Rule, bool) {
+	if !strings.HasPrefix(token, "+") {
+		return Rule{}, false
+	}
+	name := strings.TrimPrefix(token, "+")
+	arg := ""
+	if idx := strings.Index(name, "="); idx != -1 {
+		arg = name[idx+1:]
+		name = name[:idx]
+	}
+	for _, ruleName := range ruleNames {
+		if name == ruleName {
+			return Rule{Name: name, Arg: arg}, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (r Rule) String() string {
+	if r.Arg == "" {
+		return "+" + r.Name
+	}
+	return "+" + r.Name + "=" + r.Arg
+}
+
+// Check returns an error describing how v violates the rule. Nil pointers are considered unconfigured and only violate
+// "+nonempty".
+func (r Rule) Check(v reflect.Value) error {
+	if r.Name == "nonempty" {
+		if isEmpty(v) {
+			return errors.New("must not be empty")
+		}
+		return nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch r.Name {
+	case "min", "max":
+		return r.checkBound(v)
+
+	case "oneof":
+		s := fmt.Sprintf("%v", v.Interface())
+		for _, option := range strings.Split(r.Arg, "|") {
+			if s == option {
+				return nil
+			}
+		}
+		return errors.Errorf("'%s' is not one of [%s]", s, strings.ReplaceAll(r.Arg, "|", ", "))
+
+	case "pattern":
+		if v.Kind() != reflect.String {
+			return errors.Errorf("rule '%s' requires a string, not '%s'", r, v.Type())
+		}
+		pattern, err := regexp.Compile(r.Arg)
+		if err != nil {
+			return errors.Wrapf(err, "invalid rule '%s'", r)
+		}
+		if !pattern.MatchString(v.String()) {
+			return errors.Errorf("'%s' does not match pattern '%s'", v.String(), r.Arg)
+		}
+		return nil
+	}
+	return errors.Errorf("unknown rule '%s'", r)
+}
+
+func (r Rule) checkBound(v reflect.Value) error {
+	var value float64
+	parse := func(arg string) (float64, error) { return strconv.ParseFloat(arg, 64) }
+	describe := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		value = float64(v.Len())
+		describe = func(f float64) string { return fmt.Sprintf("length %d", int(f)) }
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			parse = func(arg string) (float64, error) {
+				d, err := time.ParseDuration(arg)
+				return float64(d), err
+			}
+			describe = func(f float64) string { return time.Duration(f).String() }
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(v.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+
+	default:
+		return errors.Errorf("rule '%s' not supported for '%s'", r, v.Type())
+	}
+	bound, err := parse(r.Arg)
+	if err != nil {
+		return errors.Wrapf(err, "invalid rule '%s'", r)
+	}
+	if r.Name == "min" && value < bound {
+		return errors.Errorf("%s is less than minimum %s", describe(value), describe(bound))
+	}
+	if r.Name == "max" && value > bound {
+		return errors.Errorf("%s is greater than maximum %s", describe(value), describe(bound))
+	}
+	return nil
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,171 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rulesListener struct {
+	Port     int           `cf:"+min=1,+max=65535"`
+	Protocol string        `cf:"+default=tcp,+oneof=tcp|udp|tls"`
+	Name     string        `cf:"+pattern=^[a-z]+$"`
+	Timeout  time.Duration `cf:"+min=1s"`
+	Tags     []string      `cf:"+nonempty,+max=2"`
+}
+
+func TestRules(t *testing.T) {
+	rules := Rules(reflect.TypeOf(rulesListener{}).Field(0))
+	assert.Equal(t, []Rule{{Name: "min", Arg: "1"}, {Name: "max", Arg: "65535"}}, rules)
+	assert.Equal(t, "+max=65535", rules[1].String())
+}
+
+func TestRulesTagCommas(t *testing.T) {
+	root := &struct {
+		Code  string `cf:"+pattern=^[a-z]{2,3}$,+required"`
+		Hosts string `cf:"upstream,+default=a,b"`
+	}{}
+
+	field, _ := reflect.TypeOf(root).Elem().FieldByName("Code")
+	assert.Equal(t, []Rule{{Name: "pattern", Arg: "^[a-z]{2,3}$"}}, Rules(field))
+
+	err := Bind(root, map[string]interface{}{"code": "abc"}, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", root.Code)
+	assert.Equal(t, "a,b", root.Hosts)
+
+	err = Bind(root, map[string]interface{}{"code": "abcd"}, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "code: 'abcd' does not match pattern '^[a-z]{2,3}$'", err.Error())
+}
+
+func TestRulesValid(t *testing.T) {
+	root := &rulesListener{}
+	data := map[string]interface{}{
+		"port":    1280,
+		"name":    "router",
+		"timeout": "5s",
+		"tags":    []string{"a"},
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", root.Protocol)
+}
+
+func TestRulesOptional(t *testing.T) {
+	root := &struct {
+		Mode string   `cf:"+oneof=a|b"`
+		Name string   `cf:"+pattern=^[a-z]+$"`
+		Port int      `cf:"+min=1"`
+		Tags []string `cf:"+nonempty"`
+	}{}
+
+	err := Bind(root, map[string]interface{}{}, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "tags: must not be empty", err.Error())
+
+	err = Bind(root, map[string]interface{}{"tags": []string{"a"}}, DefaultOptions())
+	assert.Nil(t, err)
+
+	err = Bind(root, map[string]interface{}{"mode": "c", "tags": []string{"a"}}, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "mode: 'c' is not one of [a, b]", err.Error())
+}
+
+func TestRulesViolated(t *testing.T) {
+	root := &struct {
+		Listeners []*rulesListener
+	}{}
+	data := map[string]interface{}{
+		"listeners": []interface{}{
+			map[string]interface{}{
+				"port":     0,
+				"protocol": "sctp",
+				"name":     "Router1",
+				"timeout":  "5ms",
+				"tags":     []string{"a", "b", "c"},
+			},
+			map[string]interface{}{
+				"port":    "oops",
+				"name":    "router",
+				"timeout": "1s",
+			},
+		},
+	}
+
+	opt := DefaultOptions()
+	opt.AggregateErrors = true
+	err := Bind(root, data, opt)
+	assert.NotNil(t, err)
+	errs := err.(BindErrors)
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		"listeners[0].port: 0 is less than minimum 1",
+		"listeners[0].protocol: 'sctp' is not one of [tcp, udp, tls]",
+		"listeners[0].name: 'Router1' does not match pattern '^[a-z]+$'",
+		"listeners[0].timeout: 5ms is less than minimum 1s",
+		"listeners[0].tags: length 3 is greater than maximum length 2",
+		"listeners[1].port: invalid value [oops] for [int]",
+		"listeners[1].tags: must not be empty",
+	}, msgs)
+}
+
+type validatedRange struct {
+	Low  int
+	High int
+}
+
+func (r *validatedRange) Validate() error {
+	if r.Low > r.High {
+		return errors.Errorf("low (%d) must not exceed high (%d)", r.Low, r.High)
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	root := &struct {
+		Range  validatedRange
+		Ranges []*validatedRange
+	}{}
+	data := map[string]interface{}{
+		"range": map[string]interface{}{"low": 1, "high": 2},
+		"ranges": []interface{}{
+			map[string]interface{}{"low": 1, "high": 2},
+			map[string]interface{}{"low": 3, "high": 2},
+		},
+	}
+
+	err := Bind(root, data, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "ranges[1]: low (3) must not exceed high (2)", err.Error())
+
+	data["ranges"] = []interface{}{}
+	err = Bind(root, data, DefaultOptions())
+	assert.Nil(t, err)
+
+	err = Bind(&validatedRange{}, map[string]interface{}{"low": 5}, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "low (5) must not exceed high (0)", err.Error())
+}
@@ -0,0 +1,161 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+)
+
+const redacted = "<SECRET>"
+
+// Secret holds a sensitive value, such as a password or a key. It redacts itself when formatted (with any verb) or
+// marshaled to json or yaml, so that it does not leak through logs, error messages or panics. Reveal returns the
+// value.
+//
+// DefaultOptions registers a setter for Secret fields, which bind from string data, and a getter for Unbind. Dump
+// treats Secret fields as though they were tagged '+secret'.
+type Secret struct {
+	value []byte
+}
+
+func NewSecret(value string) Secret {
+	return Secret{value: []byte(value)}
+}
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s.value)
+}
+
+// IsSet reports whether the secret holds a non-empty value.
+func (s Secret) IsSet() bool {
+	return len(s.value) > 0
+}
+
+// Zero overwrites the memory holding the secret value, and empties the secret. Strings previously returned by Reveal
+// are not affected.
+func (s *Secret) Zero() {
+	for i := range s.value {
+		s.value[i] = 0
+	}
+	s.value = nil
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return "cf.Secret(" + redacted + ")"
+}
+
+// Format redacts the secret for every fmt verb, so that "%d" or "%x" do not print the bytes of the value.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = io.WriteString(f, s.GoString())
+		return
+	}
+	_, _ = io.WriteString(f, redacted)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return redacted, nil
+}
+
+var secretType = reflect.TypeOf(Secret{})
+
+func secretSetter(v interface{}, f reflect.Value, _ *Options) error {
+	if vt, ok := v.(string); ok {
+		if f.Kind() == reflect.Ptr {
+			f.Elem().Set(reflect.ValueOf(NewSecret(vt)))
+		} else {
+			f.Set(reflect.ValueOf(NewSecret(vt)))
+		}
+		return nil
+	}
+	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
+}
+
+func secretGetter(f reflect.Value, _ *Options) (interface{}, error) {
+	return f.Interface().(Secret).Reveal(), nil
+}
+
+// SecretMask selects how dumps show secret values.
+type SecretMask int
+
+const (
+	// MaskPlaceholder replaces secret values with Options.SecretPlaceholder.
+	MaskPlaceholder SecretMask = iota
+
+	// MaskFingerprint shows a short fingerprint of secret values, so that they can be compared. With a
+	// DumpOptions.FingerprintKey, the fingerprint is an HMAC-SHA256 keyed with it, which cannot be checked against a
+	// dictionary of likely secrets without the key. Without a key, the fingerprint is a plain SHA-256, which a
+	// dictionary reverses for short (weak) secrets, so secrets shorter than minMaskedLength are shown as the placeholder.
+	MaskFingerprint
+
+	// MaskLast4 shows the last 4 characters of secret values of at least minMaskedLength characters.
+	MaskLast4
+)
+
+// minMaskedLength is the length below which secrets are too short to show any part (or unkeyed hash) of.
+const minMaskedLength = 12
+
+// maskSecret returns the dumped form of the secret value v (either a Secret or a field tagged '+secret').
+func maskSecret(v reflect.Value, opt *Options) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return opt.secretPlaceholder()
+		}
+		v = v.Elem()
+	}
+	var value string
+	switch {
+	case v.Type() == secretType:
+		value = v.Interface().(Secret).Reveal()
+	case v.Kind() == reflect.String:
+		value = v.String()
+	default:
+		return opt.secretPlaceholder()
+	}
+	switch opt.DumpOptions.SecretMask {
+	case MaskFingerprint:
+		if key := opt.DumpOptions.FingerprintKey; len(key) > 0 && value != "" {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(value))
+			return "<SECRET hmac:" + hex.EncodeToString(mac.Sum(nil)[:4]) + ">"
+		}
+		if len([]rune(value)) >= minMaskedLength {
+			sum := sha256.Sum256([]byte(value))
+			return "<SECRET sha256:" + hex.EncodeToString(sum[:4]) + ">"
+		}
+	case MaskLast4:
+		if runes := []rune(value); len(runes) >= minMaskedLength {
+			return "<SECRET ..." + string(runes[len(runes)-4:]) + ">"
+		}
+	}
+	return opt.secretPlaceholder()
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+type secretCf struct {
+	Id       string
+	ApiKey   Secret
+	Password string `cf:"+secret"`
+	Token    *Secret
+}
+
+func TestSecret(t *testing.T) {
+	cf := &secretCf{}
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(name string) (interface{}, bool) { return "0123456789abcdef", name == "key" })
+	err := Bind(cf, map[string]interface{}{"id": "a", "api_key": "${key}", "password": "hunter2", "token": "t0k3n"}, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "0123456789abcdef", cf.ApiKey.Reveal())
+	assert.Equal(t, "t0k3n", cf.Token.Reveal())
+
+	assert.Equal(t, "<SECRET>", fmt.Sprintf("%v", cf.ApiKey))
+	assert.Equal(t, "cf.Secret(<SECRET>)", fmt.Sprintf("%#v", cf.ApiKey))
+	for _, verb := range []string{"%s", "%d", "%x", "%o", "%b", "%q", "%+v"} {
+		assert.Equal(t, "<SECRET>", fmt.Sprintf(verb, cf.ApiKey), verb)
+	}
+	assert.Equal(t, "<SECRET>", fmt.Sprintf("%d", cf.Token))
+	assert.NotContains(t, fmt.Sprintf("%+v", cf), "0123456789abcdef")
+	out, err := json.Marshal(cf)
+	assert.Nil(t, err)
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out, &decoded))
+	assert.Equal(t, map[string]interface{}{"Id": "a", "ApiKey": "<SECRET>", "Password": "hunter2", "Token": "<SECRET>"}, decoded)
+	out, err = yaml.Marshal(cf)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(out), "0123456789abcdef")
+
+	data, err := Unbind(cf, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "0123456789abcdef", data["api_key"])
+
+	fs, err := NewFlagSet("test", cf, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "", fs.Lookup("api_key").DefValue)
+	assert.Nil(t, fs.Parse([]string{"-api_key", "from-flag"}))
+	assert.Equal(t, "from-flag", cf.ApiKey.Reveal())
+
+	err = Bind(cf, map[string]interface{}{"api_key": 1}, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "api_key: got [int], expected [cf.Secret]", err.Error())
+
+	s := NewSecret("wipe me")
+	revealed := s.value
+	s.Zero()
+	assert.False(t, s.IsSet())
+	assert.Equal(t, make([]byte, 7), revealed)
+}
+
+func TestDumpSecretMask(t *testing.T) {
+	cf := &secretCf{Id: "a", ApiKey: NewSecret("0123456789abcdef"), Password: "hunter2"}
+
+	opt := DefaultOptions()
+	expected := "{\n" +
+		"\tid       = \"a\"\n" +
+		"\tapi_key  = <SECRET>\n" +
+		"\tpassword = <SECRET>\n" +
+		"\ttoken    = <nil>\n" +
+		"}"
+	assert.Equal(t, expected, Dump(cf, opt))
+
+	opt.DumpOptions.SecretMask = MaskLast4
+	out := Dump(cf, opt)
+	assert.Contains(t, out, "api_key  = <SECRET ...cdef>")
+	assert.Contains(t, out, "password = <SECRET>") // too short to show any of it
+
+	opt.DumpOptions.SecretMask = MaskFingerprint
+	out = Dump(cf, opt)
+	assert.Contains(t, out, "api_key  = <SECRET sha256:9f9f5111>")
+	assert.Contains(t, out, "password = <SECRET>") // too short for an unkeyed fingerprint
+
+	opt.DumpOptions.FingerprintKey = []byte("dump key")
+	out = Dump(cf, opt)
+	assert.Regexp(t, `api_key  = <SECRET hmac:[0-9a-f]{8}>`, out)
+	assert.Regexp(t, `password = <SECRET hmac:[0-9a-f]{8}>`, out)
+	assert.NotContains(t, out, "9f9f5111")
+	opt.DumpOptions.FingerprintKey = nil
+
+	doc, err := DumpYaml(cf, opt)
+	assert.Nil(t, err)
+	assert.Contains(t, doc, "api_key: <SECRET sha256:9f9f5111>")
+	assert.NotContains(t, doc, "hunter2")
+}
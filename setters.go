@@ -19,193 +19,55 @@ package cf
 import (
 	"github.com/pkg/errors"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func intSetter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
+	if f.Kind() == reflect.Ptr {
+		f = f.Elem()
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func int8Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(int8); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
-	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func uint8Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(uint8); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
+	vt, err := toInt64(v, f.Type())
+	if err != nil {
+		return err
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
+	f.SetInt(vt)
+	return nil
 }
 
-func int16Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(int16); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
+func uintSetter(v interface{}, f reflect.Value, _ *Options) error {
+	if f.Kind() == reflect.Ptr {
+		f = f.Elem()
 	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
+	vt, err := toUint64(v, f.Type())
+	if err != nil {
+		return err
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
+	f.SetUint(vt)
+	return nil
 }
 
-func uint16Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(uint16); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
+func floatSetter(v interface{}, f reflect.Value, _ *Options) error {
+	if f.Kind() == reflect.Ptr {
+		f = f.Elem()
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func int32Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(int32); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
+	vt, err := toFloat64(v, f.Type())
+	if err != nil {
+		return err
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
+	f.SetFloat(vt)
+	return nil
 }
 
-func uint32Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(uint32); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
-	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func int64Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(int64); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(vt)
-		} else {
-			f.SetInt(vt)
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetInt(int64(vt))
-		} else {
-			f.SetInt(int64(vt))
-		}
-		return nil
-	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func uint64Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(uint64); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(vt)
-		} else {
-			f.SetUint(vt)
-		}
-		return nil
-	}
-	if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetUint(uint64(vt))
-		} else {
-			f.SetUint(uint64(vt))
-		}
-		return nil
-	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func float64Setter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(float64); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetFloat(vt)
-		} else {
-			f.SetFloat(vt)
-		}
-		return nil
-	} else if vt, ok := v.(int); ok {
-		if f.Kind() == reflect.Ptr {
-			f.Elem().SetFloat(float64(vt))
-		} else {
-			f.SetFloat(float64(vt))
+func boolSetter(v interface{}, f reflect.Value, _ *Options) error {
+	if vt, ok := v.(string); ok {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(vt))
+		if err != nil {
+			return errors.Errorf("invalid value [%s] for [%s]", vt, f.Type())
 		}
-		return nil
+		v = parsed
 	}
-	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
-}
-
-func boolSetter(v interface{}, f reflect.Value, _ *Options) error {
 	if vt, ok := v.(bool); ok {
 		if f.Kind() == reflect.Ptr {
 			f.Elem().SetBool(vt)
@@ -217,15 +79,8 @@ func boolSetter(v interface{}, f reflect.Value, _ *Options) error {
 	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
 }
 
-func stringSetter(v interface{}, f reflect.Value, opt *Options) error {
+func stringSetter(v interface{}, f reflect.Value, _ *Options) error {
 	if vt, ok := v.(string); ok {
-		if inlineVariablesFound(vt) {
-			replaced, err := replaceInlineVariables(vt, opt)
-			if err != nil {
-				return err
-			}
-			vt = replaced
-		}
 		if f.Kind() == reflect.Ptr {
 			f.Elem().SetString(vt)
 		} else {
@@ -237,11 +92,15 @@ func stringSetter(v interface{}, f reflect.Value, opt *Options) error {
 }
 
 func timeDurationSetter(v interface{}, f reflect.Value, _ *Options) error {
-	if vt, ok := v.(string); ok {
-		duration, err := time.ParseDuration(vt)
-		if err != nil {
+	duration, ok := v.(time.Duration) // as from a variable resolver
+	if vt, isString := v.(string); isString {
+		var err error
+		if duration, err = time.ParseDuration(vt); err != nil {
 			return err
 		}
+		ok = true
+	}
+	if ok {
 		if f.Kind() == reflect.Ptr {
 			f.Elem().SetInt(int64(duration))
 		} else {
@@ -251,3 +110,7 @@ func timeDurationSetter(v interface{}, f reflect.Value, _ *Options) error {
 	}
 	return errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(v), f.Type())
 }
+
+func timeDurationGetter(f reflect.Value, _ *Options) (interface{}, error) {
+	return time.Duration(f.Int()).String(), nil
+}
@@ -0,0 +1,203 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"encoding"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Unbind is the reverse of Bind. It returns the data that Bind would accept to produce cf, naming fields with the
+// NameConverter and cf tags, and converting values through the registered Getters.
+//
+// '+skip' fields are left out, as are nil pointers, interfaces, slices and maps. Values implementing
+// encoding.TextMarshaler are unbound as text. The 'type' of a flexible (interface{}) value cannot be recovered from the
+// concrete value; types bound by flexible setters need a Getter that includes it. Pointer cycles cannot be represented
+// as data, and fail with the path at which the cycle is found.
+func Unbind(cf interface{}, opt *Options) (map[string]interface{}, error) {
+	return unbind(cf, opt, false)
+}
+
+func unbind(cf interface{}, opt *Options, redact bool) (map[string]interface{}, error) {
+	u := &unbinder{opt: opt, redact: redact, visited: make(map[pointerVisit]string)}
+	cfV := reflect.ValueOf(cf)
+	if cfV.Kind() == reflect.Ptr {
+		u.visited[pointerVisit{cfV.Type(), cfV.Pointer()}] = opt.path
+		cfV = cfV.Elem()
+	}
+	if cfV.Kind() != reflect.Struct {
+		return nil, errors.Errorf("provided type [%s] is not a struct", cfV.Type())
+	}
+	return u.unbindStruct(cfV, opt.path)
+}
+
+type unbinder struct {
+	opt     *Options
+	redact  bool                    // mask secret values, as configured by DumpOptions.SecretMask
+	visited map[pointerVisit]string // pointers being unbound, with their paths, for cycle detection
+}
+
+// pointerVisit identifies a pointer being followed. The type is part of the key, since a pointer to a struct and a
+// pointer to its first field share an address.
+type pointerVisit struct {
+	t reflect.Type
+	p uintptr
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func (u *unbinder) unbindStruct(v reflect.Value, path string) (map[string]interface{}, error) {
+	out := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).CanInterface() {
+			fd := parseFieldData(v.Type().Field(i), u.opt)
+			if fd.skip || isNil(v.Field(i)) {
+				continue
+			}
+			if fd.secret && u.redact {
+				out[fd.name] = maskSecret(v.Field(i), u.opt)
+			} else {
+				data, err := u.unbindValue(v.Field(i), joinPath(path, fd.name))
+				if err != nil {
+					return nil, err
+				}
+				out[fd.name] = data
+			}
+		}
+	}
+	return out, nil
+}
+
+func (u *unbinder) unbindValue(v reflect.Value, path string) (interface{}, error) {
+	if u.redact && v.Type() == secretType {
+		return maskSecret(v, u.opt), nil
+	}
+	if getter, found := u.opt.Getters[v.Type()]; found {
+		data, err := getter(v, u.opt.at(path))
+		if err != nil {
+			return nil, &BindError{Path: path, Err: err}
+		}
+		return data, nil
+	}
+	if isNil(v) {
+		return nil, nil
+	}
+	if tm, ok := textMarshaler(v); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, &BindError{Path: path, Err: err}
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		visit := pointerVisit{v.Type(), v.Pointer()}
+		if at, found := u.visited[visit]; found {
+			if at == "" {
+				at = "<root>"
+			}
+			return nil, &BindError{Path: path, Err: errors.Errorf("pointer cycle to '%s'", at)}
+		}
+		u.visited[visit] = path
+		defer delete(u.visited, visit)
+		return u.unbindValue(v.Elem(), path)
+
+	case reflect.Interface:
+		return u.unbindValue(v.Elem(), path)
+
+	case reflect.Struct:
+		return u.unbindStruct(v, path)
+
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			data, err := u.unbindValue(v.Index(i), indexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, data)
+		}
+		return out, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, &BindError{Path: path, Err: errors.Errorf("unsupported map key type '%s'", v.Type().Key())}
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		out := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			data, err := u.unbindValue(v.MapIndex(k), joinPath(path, k.String()))
+			if err != nil {
+				return nil, err
+			}
+			out[k.String()] = data
+		}
+		return out, nil
+
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= math.MinInt && v.Int() <= math.MaxInt {
+			return int(v.Int()), nil
+		}
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() <= math.MaxInt {
+			return int(v.Uint()), nil
+		}
+		return v.Uint(), nil
+
+	case reflect.Float32:
+		// through the shortest decimal form, so that float32(0.1) unbinds as 0.1
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		return f, nil
+
+	case reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.String:
+		return v.String(), nil
+	}
+	return nil, &BindError{Path: path, Err: errors.Errorf("no getter for type '%s/%v'", v.Type(), v.Kind())}
+}
+
+// textMarshaler returns v (or a pointer to v) as an encoding.TextMarshaler, when it implements it.
+func textMarshaler(v reflect.Value) (encoding.TextMarshaler, bool) {
+	if v.Type().Implements(textMarshalerType) {
+		return v.Interface().(encoding.TextMarshaler), true
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		return v.Addr().Interface().(encoding.TextMarshaler), true
+	}
+	return nil, false
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return !v.IsValid()
+}
@@ -0,0 +1,144 @@
+/*
+   Copyright NetFoundry, Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   https://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type unbindListener struct {
+	Address string
+	Timeout time.Duration
+}
+
+type unbindConfig struct {
+	Name      string `cf:"service_name"`
+	Port      uint16
+	Ratio     float32
+	Enabled   *bool
+	Missing   *int
+	Password  string `cf:"+secret"`
+	Internal  string `cf:"+skip"`
+	Bind      net.IP
+	Listeners []*unbindListener
+	Routes    map[string]unbindListener
+	Tags      []string
+}
+
+func TestUnbind(t *testing.T) {
+	enabled := true
+	cfg := &unbindConfig{
+		Name:     "controller",
+		Port:     1280,
+		Ratio:    0.1,
+		Enabled:  &enabled,
+		Password: "s3cr3t",
+		Internal: "not configurable",
+		Bind:     net.ParseIP("10.0.0.1"),
+		Listeners: []*unbindListener{
+			{Address: "tls:0.0.0.0:6262", Timeout: 30 * time.Second},
+		},
+		Routes: map[string]unbindListener{
+			"edge": {Address: "tls:0.0.0.0:443"},
+		},
+	}
+
+	data, err := Unbind(cfg, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"service_name": "controller",
+		"port":         1280,
+		"ratio":        0.1,
+		"enabled":      true,
+		"password":     "s3cr3t",
+		"bind":         "10.0.0.1",
+		"listeners": []interface{}{
+			map[string]interface{}{"address": "tls:0.0.0.0:6262", "timeout": "30s"},
+		},
+		"routes": map[string]interface{}{
+			"edge": map[string]interface{}{"address": "tls:0.0.0.0:443", "timeout": "0s"},
+		},
+	}, data)
+
+	// round trip through yaml and Bind
+	out, err := yaml.Marshal(data)
+	assert.Nil(t, err)
+	var decoded map[string]interface{}
+	assert.Nil(t, yaml.Unmarshal(out, &decoded))
+	rebound := &unbindConfig{}
+	assert.Nil(t, Bind(rebound, decoded, DefaultOptions()))
+	cfg.Internal = ""
+	assert.Equal(t, cfg, rebound)
+}
+
+type unbindLevel int
+
+func TestUnbindGetter(t *testing.T) {
+	cfg := &struct {
+		Level unbindLevel
+		Any   interface{}
+		Keys  map[int]string
+	}{Level: 2, Any: &unbindListener{Address: "a"}}
+
+	opt := DefaultOptions()
+	opt.AddGetter(reflect.TypeOf(unbindLevel(0)), func(f reflect.Value, _ *Options) (interface{}, error) {
+		return []string{"low", "medium", "high"}[f.Int()], nil
+	})
+	opt.AddGetter(reflect.TypeOf(&unbindListener{}), func(f reflect.Value, opt *Options) (interface{}, error) {
+		data, err := Unbind(f.Interface(), opt)
+		if err != nil {
+			return nil, err
+		}
+		data["type"] = "listener"
+		return data, nil
+	})
+
+	data, err := Unbind(cfg, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "high", data["level"])
+	assert.Equal(t, map[string]interface{}{"type": "listener", "address": "a", "timeout": "0s"}, data["any"])
+
+	cfg.Keys = map[int]string{1: "one"}
+	_, err = Unbind(cfg, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "keys: unsupported map key type 'int'", err.Error())
+}
+
+func TestUnbindCycle(t *testing.T) {
+	n := &dumpCfNode{Name: "node"}
+	n.Parent = &dumpCfNode{Name: "parent", Parent: n}
+
+	_, err := Unbind(n, DefaultOptions())
+	assert.NotNil(t, err)
+	assert.Equal(t, "parent.parent: pointer cycle to '<root>'", err.Error())
+
+	_, err = DumpYaml(n, DefaultOptions())
+	assert.NotNil(t, err)
+	_, err = DumpJson(n, DefaultOptions())
+	assert.NotNil(t, err)
+
+	// shared, but not cyclic
+	shared := &unbindListener{Address: "a"}
+	data, err := Unbind(&struct{ A, B *unbindListener }{shared, shared}, DefaultOptions())
+	assert.Nil(t, err)
+	assert.Equal(t, data["a"], data["b"])
+}
@@ -1,49 +1,276 @@
 package cf
 
 import (
+	"encoding"
+	"fmt"
 	"github.com/pkg/errors"
-	"regexp"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
-func init() {
-	variableRegex = regexp.MustCompile("^\\$\\{([a-zA-Z0-9_.]+)\\}$")
-	inlineVariableRegex = regexp.MustCompile("\\$\\{([a-zA-Z0-9_.]+)\\}")
-}
-
-var variableRegex *regexp.Regexp
-var inlineVariableRegex *regexp.Regexp
+// maxVariableDepth limits how deeply variables may expand into other variables.
+const maxVariableDepth = 16
 
+// variableReference returns the expression of a string consisting of a single variable reference ("${expression}").
 func variableReference(v interface{}) (string, bool) {
 	if vt, ok := v.(string); ok {
-		if vmatch := variableRegex.FindSubmatch([]byte(strings.TrimSpace(vt))); vmatch != nil {
-			return string(vmatch[1]), true
+		vt = strings.TrimSpace(vt)
+		if referenceAt(vt, 0) && closingBrace(vt, 0) == len(vt)-1 {
+			return vt[2 : len(vt)-1], true
 		}
 	}
 	return "", false
 }
 
+// expandVariables replaces the variables in v, when v is a string. A string consisting of a single variable reference
+// is replaced by the (possibly non-string) value of the variable; any other string has its variables replaced inline.
+func expandVariables(v interface{}, opt *Options) (interface{}, error) {
+	if vname, ok := variableReference(v); ok {
+		return lookupVariable(vname, opt)
+	}
+	if vt, ok := v.(string); ok && inlineVariablesFound(vt) {
+		return replaceInlineVariables(vt, opt)
+	}
+	return v, nil
+}
+
+// expandData returns a copy of the data tree v with the variables in every string expanded, including those in map
+// keys and slice elements. Failures are reported as a *BindError positioned below path.
+func expandData(v interface{}, path string, opt *Options) (interface{}, error) {
+	switch vt := v.(type) {
+	case string:
+		expanded, err := expandVariables(vt, opt)
+		if err != nil {
+			return nil, &BindError{Path: path, Err: err}
+		}
+		return expanded, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vt))
+		for k, kv := range vt {
+			key := k
+			if inlineVariablesFound(k) {
+				expanded, err := expandString(k, opt, nil)
+				if err != nil {
+					return nil, &BindError{Path: joinPath(path, k), Err: err}
+				}
+				_, literal := vt[expanded]
+				_, expandedBefore := out[expanded]
+				if literal || expandedBefore {
+					return nil, &BindError{Path: joinPath(path, k), Err: errors.Errorf("duplicate key '%s'", expanded)}
+				}
+				key = expanded
+			}
+			value, err := expandData(kv, joinPath(path, key), opt)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+		}
+		return out, nil
+
+	case map[interface{}]interface{}:
+		return expandData(MapIToMapS(vt), path, opt)
+
+	case []interface{}:
+		out := make([]interface{}, len(vt))
+		for i, iv := range vt {
+			value, err := expandData(iv, indexPath(path, i), opt)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	}
+
+	// other slices ([]string, []map[string]interface{}, ...) keep their type
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		out := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			value, err := expandData(rv.Index(i).Interface(), indexPath(path, i), opt)
+			if err != nil {
+				return nil, err
+			}
+			vv := reflect.ValueOf(value)
+			if !vv.IsValid() || !vv.Type().AssignableTo(rv.Type().Elem()) {
+				return nil, &BindError{Path: indexPath(path, i), Err: errors.Errorf("got [%s], expected [%s]", reflect.TypeOf(value), rv.Type().Elem())}
+			}
+			out.Index(i).Set(vv)
+		}
+		return out.Interface(), nil
+	}
+	return v, nil
+}
+
+// lookupVariable resolves a variable expression, which is a variable name optionally followed by shell-style
+// modifiers: "name:-default" falls back to default when the variable is unset or empty, and "name:?message" fails with
+// message when the variable is unset or empty.
+//
+// String values containing further variables are expanded recursively, up to maxVariableDepth; a variable that
+// expands into itself is reported with the chain of variables involved ("a -> b -> a").
+func lookupVariable(expr string, opt *Options) (interface{}, error) {
+	return expandExpression(expr, opt, nil)
+}
+
+func expandExpression(expr string, opt *Options, chain []string) (interface{}, error) {
+	name, modifier, arg := expr, "", ""
+	if idx := strings.Index(expr, ":-"); idx != -1 {
+		name, modifier, arg = expr[:idx], ":-", expr[idx+2:]
+	}
+	if idx := strings.Index(expr, ":?"); idx != -1 && (modifier == "" || idx < len(name)) {
+		name, modifier, arg = expr[:idx], ":?", expr[idx+2:]
+	}
+	for _, link := range chain {
+		if link == name {
+			return nil, errors.Errorf("variable cycle %s -> %s", strings.Join(chain, " -> "), name)
+		}
+	}
+	if len(chain) >= maxVariableDepth {
+		return nil, errors.Errorf("variables nested deeper than %d: %s -> %s", maxVariableDepth, strings.Join(chain, " -> "), name)
+	}
+
+	vvalue, found := opt.resolveVariable(name)
+	if found && (modifier == "" || vvalue != "") {
+		if vs, ok := vvalue.(string); ok && inlineVariablesFound(vs) {
+			return expandString(vs, opt, append(chain[:len(chain):len(chain)], name))
+		}
+		return vvalue, nil
+	}
+	switch modifier {
+	case ":-":
+		return expandString(arg, opt, chain)
+	case ":?":
+		if arg == "" {
+			arg = "must be set"
+		}
+		return nil, errors.Errorf("variable '${%s}': %s", name, arg)
+	}
+	return nil, errors.Errorf("unable to resolve variable '${%s}'", name)
+}
+
 func inlineVariablesFound(in string) bool {
-	vmatch := inlineVariableRegex.FindSubmatch([]byte(strings.TrimSpace(in)))
-	return vmatch != nil
+	for i := range in {
+		if referenceAt(in, i) && closingBrace(in, i) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceAt reports whether s has a variable reference starting at i: "${" followed by the start of a variable name
+// (a letter, digit, '_' or '.'). Anything else, such as "${}", is kept literally.
+func referenceAt(s string, i int) bool {
+	if !strings.HasPrefix(s[i:], "${") || len(s) < i+3 {
+		return false
+	}
+	c := s[i+2]
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
 }
 
 func replaceInlineVariables(in string, opt *Options) (string, error) {
-	out := strings.TrimSpace(in)
-	vmatch := inlineVariableRegex.FindSubmatchIndex([]byte(out))
-	for vmatch != nil {
-		vname := out[vmatch[2]:vmatch[3]]
-		vvalue, resolved := opt.resolveVariable(vname)
-		if !resolved {
-			return "", errors.Errorf("variable ${%s} not found", vname)
+	return expandString(strings.TrimSpace(in), opt, nil)
+}
+
+// expandString replaces each variable reference in s with the value of the variable. "$${" escapes a literal "${".
+func expandString(s string, opt *Options, chain []string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			// escaped; keep the reference literally
+			end := closingBrace(s, i+1)
+			if end == -1 {
+				out.WriteString(s[i+1:])
+				break
+			}
+			out.WriteString(s[i+1 : end+1])
+			i = end + 1
+
+		} else if referenceAt(s, i) {
+			end := closingBrace(s, i)
+			if end == -1 {
+				// unterminated; not a reference
+				out.WriteString(s[i:])
+				break
+			}
+			vname := s[i+2 : end]
+			vvalue, err := expandExpression(vname, opt, chain)
+			if err != nil {
+				return "", err
+			}
+			vvstring, err := formatVariable(vname, vvalue)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(vvstring)
+			i = end + 1
+
+		} else {
+			out.WriteByte(s[i])
+			i++
 		}
-		vvstring, ok := vvalue.(string)
-		if !ok {
-			return "", errors.Errorf("variable ${%s} not string value", vname)
+	}
+	return out.String(), nil
+}
+
+// formatVariable formats the value of a variable for substitution into a string. Strings, booleans, numbers,
+// fmt.Stringer (such as time.Duration) and encoding.TextMarshaler values are supported.
+func formatVariable(vname string, v interface{}) (string, error) {
+	switch vt := v.(type) {
+	case string:
+		return vt, nil
+	case encoding.TextMarshaler:
+		text, err := vt.MarshalText()
+		if err != nil {
+			return "", errors.Wrapf(err, "variable ${%s}", vname)
 		}
-		out = out[:vmatch[0]]+vvstring+out[vmatch[1]:]
+		return string(text), nil
+	case fmt.Stringer:
+		return vt.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return fmt.Sprintf("%v", vt), nil
+	case float32:
+		return strconv.FormatFloat(float64(vt), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64), nil
+	}
+	return "", errors.Errorf("variable ${%s} not scalar value (%v)", vname, reflect.TypeOf(v))
+}
+
+// closingBrace returns the index of the brace closing the reference starting with "${" at start, allowing for nested
+// references (as in "${a:-${b}}"), or -1 when the reference is unterminated.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start + 2; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// EnvVariableResolver resolves variables from the environment. AddBuiltinVariableNamespaces registers it for the "env"
+// namespace, as in "${env:HOME}".
+func EnvVariableResolver(name string) (interface{}, bool) {
+	return os.LookupEnv(name)
+}
 
-		vmatch = inlineVariableRegex.FindSubmatchIndex([]byte(out))
+// FileVariableResolver resolves variables to the contents of the file they name, with surrounding whitespace trimmed.
+// AddBuiltinVariableNamespaces registers it for the "file" namespace, as in "${file:/run/secrets/db_pass}".
+func FileVariableResolver(name string) (interface{}, bool) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, false
 	}
-	return out, nil
+	return strings.TrimSpace(string(data)), true
 }
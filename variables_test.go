@@ -1,8 +1,11 @@
 package cf
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestVariableReference(t *testing.T) {
@@ -13,3 +16,279 @@ func TestVariableReference(t *testing.T) {
 	v, found = variableReference(int16(1))
 	assert.False(t, found)
 }
+
+func TestNamespacedVariables(t *testing.T) {
+	t.Setenv("CF_TEST_HOME", "/home/cf")
+	secret := writeTestFile(t, "db_pass", "s3cr3t\n")
+
+	root := &struct {
+		DataPath string
+		Password string
+		Server   struct {
+			Port int
+		}
+		Advertise string
+		Peer      int
+		Missing   string
+	}{}
+
+	data := map[string]interface{}{
+		"data_path": "${env:CF_TEST_HOME}/data",
+		"password":  "${file:" + secret + "}",
+		"server": map[string]interface{}{
+			"port": 1280,
+		},
+		"advertise": "localhost:${self:server.port}",
+		"peer":      "${self:server.port}",
+	}
+
+	opt := DefaultOptions().AddBuiltinVariableNamespaces()
+	opt.AddVariableNamespace("custom", func(name string) (interface{}, bool) {
+		return "custom-" + name, true
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:1280", root.Advertise)
+	data["advertise"] = "${custom:advertise}"
+
+	err = Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/cf/data", root.DataPath)
+	assert.Equal(t, "s3cr3t", root.Password)
+	assert.Equal(t, "custom-advertise", root.Advertise)
+	assert.Equal(t, 1280, root.Peer)
+
+	err = Bind(root, data, DefaultOptions())
+	assert.NotNil(t, err) // not registered by default
+	assert.Equal(t, "data_path: unable to resolve variable '${env:CF_TEST_HOME}'", err.Error())
+
+	data["missing"] = "${env:CF_TEST_MISSING}"
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+}
+
+func TestVariableModifiers(t *testing.T) {
+	root := &struct {
+		Port     int
+		Host     string
+		Address  string
+		Password string
+	}{}
+
+	data := map[string]interface{}{
+		"port":    "${PORT:-8080}",
+		"host":    "${HOST:-localhost}",
+		"address": "${HOST:-0.0.0.0}:${PORT:-1280}",
+	}
+
+	opt := DefaultOptions().AddBuiltinVariableNamespaces()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		if vname == "HOST" {
+			return "", true
+		}
+		return nil, false
+	})
+
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, root.Port)
+	assert.Equal(t, "localhost", root.Host)
+	assert.Equal(t, "0.0.0.0:1280", root.Address)
+
+	data = map[string]interface{}{
+		"password": "${DB_PASS:?database password must be set}",
+	}
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "password: variable '${DB_PASS}': database password must be set", err.Error())
+
+	data = map[string]interface{}{
+		"address": "${env:CF_TEST_HOST:?}:1280",
+	}
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "address: variable '${env:CF_TEST_HOST}': must be set", err.Error())
+}
+
+func TestNestedVariables(t *testing.T) {
+	vars := map[string]interface{}{
+		"base":  "/opt/${name}",
+		"name":  "cf",
+		"data":  "${base}/data",
+		"a":     "${b}",
+		"b":     "x-${a}",
+		"self":  "${self}",
+		"fee":   "$${not.a.variable}",
+		"port":  "${port.base:-80}80",
+		"depth": "${depth1}",
+	}
+	for i := 1; i <= maxVariableDepth; i++ {
+		vars[fmt.Sprintf("depth%d", i)] = fmt.Sprintf("${depth%d}", i+1)
+	}
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		v, found := vars[vname]
+		return v, found
+	})
+
+	v, err := lookupVariable("data", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "/opt/cf/data", v)
+
+	v, err = lookupVariable("fee", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "${not.a.variable}", v)
+
+	out, err := replaceInlineVariables("$${literal} and ${name} and $${also:-literal}", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "${literal} and cf and ${also:-literal}", out)
+
+	v, err = lookupVariable("port", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "8080", v)
+
+	v, err = lookupVariable("missing:-${name}", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "cf", v)
+
+	_, err = lookupVariable("a", opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "variable cycle a -> b -> a", err.Error())
+
+	_, err = lookupVariable("self", opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "variable cycle self -> self", err.Error())
+
+	_, err = lookupVariable("depth", opt)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "variables nested deeper than 16")
+
+	root := &struct {
+		Template string
+	}{}
+	err = Bind(root, map[string]interface{}{"template": "${fee}"}, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "${not.a.variable}", root.Template)
+
+	// not references; kept literally
+	for _, literal := range []string{"`${}`", "${ name }", "cost: $5", "${-x}", "${name"} {
+		err = Bind(root, map[string]interface{}{"template": literal}, opt)
+		assert.Nil(t, err)
+		assert.Equal(t, literal, root.Template)
+	}
+}
+
+type pluginConfig struct {
+	Path    string
+	Tags    []string
+	Escaped string
+}
+
+func TestFlexibleVariables(t *testing.T) {
+	vars := map[string]interface{}{
+		"plugin.type": "exec",
+		"plugin.home": "/opt/plugins",
+		"region":      "east",
+	}
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		v, found := vars[vname]
+		return v, found
+	})
+	var raw map[string]interface{}
+	opt.AddFlexibleSetter("exec", func(v interface{}, opt *Options) (interface{}, error) {
+		raw = v.(map[string]interface{})
+		cfg := &pluginConfig{}
+		if err := Bind(cfg, raw, opt); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	})
+
+	root := &struct {
+		Plugin  interface{}
+		Regions map[string]int
+	}{}
+	data := map[string]interface{}{
+		"plugin": map[string]interface{}{
+			"type":    "${plugin.type}",
+			"path":    "${plugin.home}/exec",
+			"tags":    []interface{}{"${region}", "static"},
+			"escaped": "$${literal}",
+		},
+		"regions": map[string]interface{}{
+			"${region}-1": 1,
+		},
+	}
+	err := Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "exec", raw["type"])
+	assert.Equal(t, []interface{}{"east", "static"}, raw["tags"])
+	cfg := root.Plugin.(*pluginConfig)
+	assert.Equal(t, "/opt/plugins/exec", cfg.Path)
+	assert.Equal(t, []string{"east", "static"}, cfg.Tags)
+	assert.Equal(t, "${literal}", cfg.Escaped)
+	assert.Equal(t, map[string]int{"east-1": 1}, root.Regions)
+
+	data = map[string]interface{}{
+		"plugin": map[string]interface{}{
+			"type": "exec",
+			"tags": []interface{}{"ok", "${missing}"},
+		},
+	}
+	err = Bind(root, data, opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "plugin.tags[1]: unable to resolve variable '${missing}'", err.Error())
+}
+
+func TestTypedVariables(t *testing.T) {
+	vars := map[string]interface{}{
+		"port":        1280,
+		"ratio":       0.25,
+		"enabled":     true,
+		"timeout":     30 * time.Second,
+		"ip":          net.ParseIP("10.0.0.1"),
+		"env.port":    "8080",
+		"env.timeout": "1m",
+		"tags":        []string{"a"},
+	}
+	opt := DefaultOptions()
+	opt.AddVariableResolver(func(vname string) (interface{}, bool) {
+		v, found := vars[vname]
+		return v, found
+	})
+
+	out, err := replaceInlineVariables("${ip}:${port} ratio=${ratio} enabled=${enabled} timeout=${timeout}", opt)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:1280 ratio=0.25 enabled=true timeout=30s", out)
+
+	_, err = replaceInlineVariables("tags=${tags}", opt)
+	assert.NotNil(t, err)
+	assert.Equal(t, "variable ${tags} not scalar value ([]string)", err.Error())
+
+	root := &struct {
+		Port     uint16
+		Timeout  time.Duration
+		Deadline time.Duration
+		Enabled  *bool
+		Name     string
+		Label    *string
+	}{}
+	data := map[string]interface{}{
+		"port":     "${env.port}",
+		"timeout":  "${env.timeout}",
+		"deadline": "${timeout}",
+		"enabled":  "${enabled}",
+		"name":     "${port}",
+		"label":    "${timeout}",
+	}
+	err = Bind(root, data, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(8080), root.Port)
+	assert.Equal(t, time.Minute, root.Timeout)
+	assert.Equal(t, 30*time.Second, root.Deadline)
+	assert.True(t, *root.Enabled)
+	assert.Equal(t, "1280", root.Name)
+	assert.Equal(t, "30s", *root.Label)
+}